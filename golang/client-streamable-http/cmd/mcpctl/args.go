@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// usageError is a mistake in a command's arguments, reported together with
+// the command's usage line.
+type usageError struct{ err error }
+
+func (e *usageError) Error() string { return e.err.Error() }
+
+func (e *usageError) Unwrap() error { return e.err }
+
+func usagef(format string, args ...any) error {
+	return &usageError{fmt.Errorf(format, args...)}
+}
+
+// parseFlags parses a command's flags, which may come before, after or
+// between its positional arguments, and returns the positional ones.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, &usageError{err}
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// argFlags collects repeated -arg name=value flags. Values that parse as
+// JSON keep their type, so -arg n=3 is a number and -arg tags='["a"]' an
+// array; anything else is a string.
+type argFlags map[string]any
+
+func (a *argFlags) String() string { return "" }
+
+func (a *argFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return errors.New("want name=value")
+	}
+	if *a == nil {
+		*a = make(argFlags)
+	}
+	var v any
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		v = value
+	}
+	(*a)[name] = v
+	return nil
+}
+
+// loadArguments builds a command's arguments from -args, which is a JSON
+// object given inline, as @file or as - for stdin, overlaid with the -arg
+// flags.
+func loadArguments(src string, overrides argFlags) (map[string]any, error) {
+	args := make(map[string]any)
+	if src != "" {
+		var data []byte
+		var err error
+		switch {
+		case src == "-":
+			data, err = io.ReadAll(os.Stdin)
+		case strings.HasPrefix(src, "@"):
+			data, err = os.ReadFile(src[1:])
+		default:
+			data = []byte(src)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read arguments: %w", err)
+		}
+		if err := json.Unmarshal(data, &args); err != nil {
+			return nil, usagef("arguments must be a JSON object: %v", err)
+		}
+	}
+	for k, v := range overrides {
+		args[k] = v
+	}
+	return args, nil
+}
+
+// stringArguments converts arguments for prompts, whose values are always
+// strings: strings are kept as they are and anything else is sent as JSON.
+func stringArguments(args map[string]any) map[string]string {
+	out := make(map[string]string, len(args))
+	for k, v := range args {
+		if s, ok := v.(string); ok {
+			out[k] = s
+			continue
+		}
+		b, _ := json.Marshal(v)
+		out[k] = string(b)
+	}
+	return out
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// catalog caches what a server offers, for the shell's tab completion.
+type catalog struct {
+	s *session
+
+	mu        sync.RWMutex
+	tools     []mcp.Tool
+	resources []mcp.Resource
+	templates []mcp.ResourceTemplate
+	prompts   []mcp.Prompt
+}
+
+// Kinds of list a catalog caches.
+const (
+	listTools     = "tools"
+	listResources = "resources"
+	listPrompts   = "prompts"
+)
+
+// listChangedKinds maps list_changed notifications to the list they concern.
+var listChangedKinds = map[string]string{
+	mcp.MethodNotificationToolsListChanged:     listTools,
+	mcp.MethodNotificationResourcesListChanged: listResources,
+	mcp.MethodNotificationPromptsListChanged:   listPrompts,
+}
+
+func newCatalog(s *session) *catalog {
+	return &catalog{s: s}
+}
+
+// refreshAll reloads every list the server supports.
+func (c *catalog) refreshAll(ctx context.Context) error {
+	var errs []error
+	for _, kind := range []string{listTools, listResources, listPrompts} {
+		if _, err := c.refresh(ctx, kind); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// refresh reloads one list and returns how many entries it now has. Lists
+// the server does not support stay empty.
+func (c *catalog) refresh(ctx context.Context, kind string) (int, error) {
+	caps := c.s.server.Capabilities
+	switch kind {
+	case listTools:
+		if caps.Tools == nil {
+			return 0, nil
+		}
+		res, err := c.s.c.ListTools(ctx, mcp.ListToolsRequest{})
+		if err != nil {
+			return 0, fmt.Errorf("list tools: %w", err)
+		}
+		c.mu.Lock()
+		c.tools = res.Tools
+		c.mu.Unlock()
+		return len(res.Tools), nil
+	case listResources:
+		if caps.Resources == nil {
+			return 0, nil
+		}
+		res, err := c.s.c.ListResources(ctx, mcp.ListResourcesRequest{})
+		if err != nil {
+			return 0, fmt.Errorf("list resources: %w", err)
+		}
+		// Not every server that has resources has templates.
+		var templates []mcp.ResourceTemplate
+		if tres, err := c.s.c.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{}); err == nil {
+			templates = tres.ResourceTemplates
+		}
+		c.mu.Lock()
+		c.resources, c.templates = res.Resources, templates
+		c.mu.Unlock()
+		return len(res.Resources), nil
+	case listPrompts:
+		if caps.Prompts == nil {
+			return 0, nil
+		}
+		res, err := c.s.c.ListPrompts(ctx, mcp.ListPromptsRequest{})
+		if err != nil {
+			return 0, fmt.Errorf("list prompts: %w", err)
+		}
+		c.mu.Lock()
+		c.prompts = res.Prompts
+		c.mu.Unlock()
+		return len(res.Prompts), nil
+	}
+	return 0, fmt.Errorf("unknown list %q", kind)
+}
+
+func (c *catalog) toolNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.tools))
+	for _, t := range c.tools {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+func (c *catalog) tool(name string) (mcp.Tool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	i := slices.IndexFunc(c.tools, func(t mcp.Tool) bool { return t.Name == name })
+	if i < 0 {
+		return mcp.Tool{}, false
+	}
+	return c.tools[i], true
+}
+
+func (c *catalog) resourceURIs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	uris := make([]string, 0, len(c.resources))
+	for _, r := range c.resources {
+		uris = append(uris, r.URI)
+	}
+	return uris
+}
+
+func (c *catalog) templateURIs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	uris := make([]string, 0, len(c.templates))
+	for _, t := range c.templates {
+		if t.URITemplate != nil && t.URITemplate.Template != nil {
+			uris = append(uris, t.URITemplate.Raw())
+		}
+	}
+	return uris
+}
+
+func (c *catalog) promptNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.prompts))
+	for _, p := range c.prompts {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func (c *catalog) promptArguments(name string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, p := range c.prompts {
+		if p.Name == name {
+			args := make([]string, 0, len(p.Arguments))
+			for _, a := range p.Arguments {
+				args = append(args, a.Name)
+			}
+			return args
+		}
+	}
+	return nil
+}
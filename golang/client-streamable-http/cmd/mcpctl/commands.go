@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/duaraghav8/gomcptest/manager"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// command is one mcpctl subcommand, named by one or two words.
+type command struct {
+	name    string
+	args    string
+	summary string
+	// long commands run until the user ends them; -timeout applies to each
+	// of their steps rather than to the whole command.
+	long bool
+	run  func(ctx context.Context, s *session, args []string, out *printer) error
+	// servers, set instead of run, works on every server in the -servers
+	// config file at once.
+	servers func(ctx context.Context, m *manager.Manager, args []string, out *printer) error
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{name: "info", summary: "show the server's name, version, capabilities and instructions", run: runInfo},
+		{name: "init", summary: "same as info", run: runInfo},
+		{name: "ping", summary: "check that the server answers", run: runPing},
+		{name: "tools list", summary: "list tools and their arguments (* marks required ones)", run: runToolsList},
+		{name: "tools call", args: "<name> [--<argument> value]... [-args JSON|@file|-] [-arg name=value]...", summary: "call a tool; tools call <name> -h lists its arguments", run: runToolsCall},
+		{name: "resources list", summary: "list resources", run: runResourcesList},
+		{name: "resources templates", summary: "list resource templates", run: runResourceTemplates},
+		{name: "resources read", args: "<uri>", summary: "read a resource", run: runResourcesRead},
+		{name: "prompts list", summary: "list prompts and their arguments", run: runPromptsList},
+		{name: "prompts get", args: "<name> [-args JSON|@file|-] [-arg name=value]...", summary: "render a prompt", run: runPromptsGet},
+		{name: "complete", args: "prompt|resource <prompt name or URI template> <argument> [value]", summary: "ask the server to complete an argument", run: runComplete},
+		{name: "servers status", summary: "connect to every server in the -servers file and show how each is doing", servers: runServersStatus},
+		{name: "servers tools", summary: "list the tools of every server in the -servers file, as server.tool", servers: runServersTools},
+		{name: "servers call", args: "<server.tool> [--<argument> value]... [-args JSON|@file|-] [-arg name=value]...", summary: "call a tool of a server in the -servers file", servers: runServersCall},
+		{name: "shell", args: "[-history file]", summary: "start an interactive shell with tab completion", long: true, run: runShell},
+	}
+}
+
+// findCommand looks up the command named by the first one or two words of
+// args and returns it with the remaining arguments.
+func findCommand(args []string) (*command, []string) {
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == c.name {
+			return c, args[len(words):]
+		}
+	}
+	return nil, nil
+}
+
+// inShell reports whether the command can run in the shell, which has one
+// session and is itself the only long command.
+func (c *command) inShell() bool {
+	return !c.long && c.servers == nil
+}
+
+// printCommands lists the commands, leaving out those that cannot run in
+// the shell unless all is set.
+func printCommands(w io.Writer, all bool) {
+	fmt.Fprintln(w, "commands:")
+	rows := make([][]string, 0, len(commands))
+	for _, c := range commands {
+		if !c.inShell() && !all {
+			continue
+		}
+		rows = append(rows, []string{"  " + strings.TrimSpace(c.name+" "+c.args), c.summary})
+	}
+	table(w, rows)
+}
+
+// noArgs rejects positional arguments for commands that take none.
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments: %s", strings.Join(args, " "))
+	}
+	return nil
+}
+
+func runInfo(ctx context.Context, s *session, args []string, out *printer) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	res := s.server
+	return out.print(res, func(w io.Writer) {
+		fmt.Fprintf(w, "Server:    %s %s\n", res.ServerInfo.Name, res.ServerInfo.Version)
+		fmt.Fprintf(w, "Protocol:  %s\n", res.ProtocolVersion)
+		if id := s.c.GetSessionId(); id != "" {
+			fmt.Fprintf(w, "Session:   %s\n", id)
+		}
+		fmt.Fprintf(w, "Supports:  %s\n", strings.Join(capabilityNames(res.Capabilities), ", "))
+		if res.Instructions != "" {
+			fmt.Fprintf(w, "\n%s\n", res.Instructions)
+		}
+	})
+}
+
+func capabilityNames(c mcp.ServerCapabilities) []string {
+	var names []string
+	if c.Tools != nil {
+		names = append(names, "tools"+listChanged(c.Tools.ListChanged))
+	}
+	if c.Resources != nil {
+		name := "resources" + listChanged(c.Resources.ListChanged)
+		if c.Resources.Subscribe {
+			name += " (subscribe)"
+		}
+		names = append(names, name)
+	}
+	if c.Prompts != nil {
+		names = append(names, "prompts"+listChanged(c.Prompts.ListChanged))
+	}
+	if c.Logging != nil {
+		names = append(names, "logging")
+	}
+	if len(names) == 0 {
+		names = append(names, "nothing")
+	}
+	return names
+}
+
+func listChanged(ok bool) string {
+	if ok {
+		return " (list_changed)"
+	}
+	return ""
+}
+
+func runPing(ctx context.Context, s *session, args []string, out *printer) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	start := time.Now()
+	if err := s.c.Ping(ctx); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+	took := time.Since(start)
+	return out.print(map[string]any{"ok": true, "ms": took.Milliseconds()}, func(w io.Writer) {
+		fmt.Fprintf(w, "ok (%s)\n", took.Round(time.Microsecond))
+	})
+}
+
+func runToolsList(ctx context.Context, s *session, args []string, out *printer) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	res, err := s.c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return fmt.Errorf("list tools: %w", err)
+	}
+	return out.print(res, func(w io.Writer) {
+		rows := [][]string{{"NAME", "ARGUMENTS", "DESCRIPTION"}}
+		for _, t := range res.Tools {
+			rows = append(rows, []string{t.Name, schemaArgs(t.InputSchema), oneLine(t.Description)})
+		}
+		table(w, rows)
+	})
+}
+
+// errToolFailed makes mcpctl exit non-zero after printing a tool result
+// with isError set.
+var errToolFailed = errors.New("tool reported an error")
+
+func runToolsCall(ctx context.Context, s *session, args []string, out *printer) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return usagef("want a tool name first")
+	}
+	name := args[0]
+	tool, err := s.tool(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	arguments, err := toolArguments("tools call", tool, args[1:], out.w)
+	if err != nil {
+		return err
+	}
+	if arguments == nil {
+		return nil
+	}
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = arguments
+	res, err := s.c.CallTool(ctx, req)
+	if err != nil {
+		return fmt.Errorf("call %s: %w", name, err)
+	}
+	return printToolResult(ctx, out, s.c, res)
+}
+
+// toolArguments builds a tool's arguments from -args, -arg and the flags
+// made from its input schema. With -h it prints the tool's help to w and
+// returns nil arguments.
+func toolArguments(cmdName string, tool mcp.Tool, args []string, w io.Writer) (map[string]any, error) {
+	fs := flag.NewFlagSet(cmdName+" "+tool.Name, flag.ContinueOnError)
+	src := fs.String("args", "", "arguments as a JSON object, @file or - for stdin")
+	var overrides argFlags
+	fs.Var(&overrides, "arg", "one argument as `name=value`")
+	typed := newSchemaFlags(fs, tool.InputSchema)
+	pos, err := parseFlags(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil, toolHelp(w, cmdName, tool, fs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := noArgs(pos); err != nil {
+		return nil, err
+	}
+	arguments, err := loadArguments(*src, overrides)
+	if err != nil {
+		return nil, err
+	}
+	if err := typed.apply(arguments); err != nil {
+		return nil, err
+	}
+	return arguments, nil
+}
+
+// printToolResult prints a tool result and turns isError into
+// errToolFailed. c reads linked resources for -follow.
+func printToolResult(ctx context.Context, out *printer, c *client.Client, res *mcp.CallToolResult) error {
+	err := out.print(res, func(w io.Writer) {
+		out.renderer(c).ToolResult(ctx, res)
+	})
+	if err == nil && res.IsError {
+		err = errToolFailed
+	}
+	return err
+}
+
+// toolHelp prints a tool's description and the flags made from its input
+// schema.
+func toolHelp(w io.Writer, cmdName string, tool mcp.Tool, fs *flag.FlagSet) error {
+	fmt.Fprintf(w, "usage: %s %s [flags]\n", cmdName, tool.Name)
+	if tool.Description != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimSpace(tool.Description))
+	}
+	fmt.Fprintln(w, "\nflags:")
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	return nil
+}
+
+func runResourcesList(ctx context.Context, s *session, args []string, out *printer) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	res, err := s.c.ListResources(ctx, mcp.ListResourcesRequest{})
+	if err != nil {
+		return fmt.Errorf("list resources: %w", err)
+	}
+	return out.print(res, func(w io.Writer) {
+		rows := [][]string{{"URI", "NAME", "TYPE", "DESCRIPTION"}}
+		for _, r := range res.Resources {
+			rows = append(rows, []string{r.URI, r.Name, r.MIMEType, oneLine(r.Description)})
+		}
+		table(w, rows)
+	})
+}
+
+func runResourceTemplates(ctx context.Context, s *session, args []string, out *printer) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	res, err := s.c.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{})
+	if err != nil {
+		return fmt.Errorf("list resource templates: %w", err)
+	}
+	return out.print(res, func(w io.Writer) {
+		rows := [][]string{{"URI TEMPLATE", "NAME", "TYPE", "DESCRIPTION"}}
+		for _, t := range res.ResourceTemplates {
+			tmpl := ""
+			if t.URITemplate != nil && t.URITemplate.Template != nil {
+				tmpl = t.URITemplate.Raw()
+			}
+			rows = append(rows, []string{tmpl, t.Name, t.MIMEType, oneLine(t.Description)})
+		}
+		table(w, rows)
+	})
+}
+
+func runResourcesRead(ctx context.Context, s *session, args []string, out *printer) error {
+	if len(args) != 1 {
+		return usagef("want exactly one URI")
+	}
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = args[0]
+	res, err := s.c.ReadResource(ctx, req)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", args[0], err)
+	}
+	return out.print(res, func(w io.Writer) {
+		r := out.renderer(s.c)
+		for _, c := range res.Contents {
+			r.ResourceBody(c)
+		}
+	})
+}
+
+func runPromptsList(ctx context.Context, s *session, args []string, out *printer) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	res, err := s.c.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	if err != nil {
+		return fmt.Errorf("list prompts: %w", err)
+	}
+	return out.print(res, func(w io.Writer) {
+		rows := [][]string{{"NAME", "ARGUMENTS", "DESCRIPTION"}}
+		for _, p := range res.Prompts {
+			var names []string
+			for _, a := range p.Arguments {
+				name := a.Name
+				if a.Required {
+					name += "*"
+				}
+				names = append(names, name)
+			}
+			rows = append(rows, []string{p.Name, strings.Join(names, " "), oneLine(p.Description)})
+		}
+		table(w, rows)
+	})
+}
+
+func runPromptsGet(ctx context.Context, s *session, args []string, out *printer) error {
+	fs := flag.NewFlagSet("prompts get", flag.ContinueOnError)
+	src := fs.String("args", "", "arguments as a JSON object, @file or - for stdin")
+	var overrides argFlags
+	fs.Var(&overrides, "arg", "one argument as name=value")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usagef("want exactly one prompt name")
+	}
+	arguments, err := loadArguments(*src, overrides)
+	if err != nil {
+		return err
+	}
+
+	req := mcp.GetPromptRequest{}
+	req.Params.Name = pos[0]
+	req.Params.Arguments = stringArguments(arguments)
+	res, err := s.c.GetPrompt(ctx, req)
+	if err != nil {
+		return fmt.Errorf("get prompt %s: %w", pos[0], err)
+	}
+	return out.print(res, func(w io.Writer) {
+		if res.Description != "" {
+			fmt.Fprintf(w, "# %s\n\n", res.Description)
+		}
+		r := out.renderer(s.c)
+		for _, m := range res.Messages {
+			fmt.Fprintf(w, "[%s]\n", m.Role)
+			r.Content(ctx, m.Content)
+		}
+	})
+}
+
+func runComplete(ctx context.Context, s *session, args []string, out *printer) error {
+	if len(args) < 3 || len(args) > 4 {
+		return usagef("want a reference kind, a reference, an argument name and optionally a value")
+	}
+	req := mcp.CompleteRequest{}
+	switch args[0] {
+	case "prompt":
+		req.Params.Ref = mcp.PromptReference{Type: "ref/prompt", Name: args[1]}
+	case "resource":
+		req.Params.Ref = mcp.ResourceReference{Type: "ref/resource", URI: args[1]}
+	default:
+		return usagef("reference kind must be prompt or resource, not %q", args[0])
+	}
+	req.Params.Argument.Name = args[2]
+	if len(args) == 4 {
+		req.Params.Argument.Value = args[3]
+	}
+	res, err := s.c.Complete(ctx, req)
+	if err != nil {
+		return fmt.Errorf("complete: %w", err)
+	}
+	return out.print(res, func(w io.Writer) {
+		for _, v := range res.Completion.Values {
+			fmt.Fprintln(w, v)
+		}
+		if res.Completion.HasMore || res.Completion.Total > len(res.Completion.Values) {
+			fmt.Fprintf(w, "(%d of %d shown)\n", len(res.Completion.Values), max(res.Completion.Total, len(res.Completion.Values)))
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// complete is the line editor's Tab handler. It completes the word before
+// the cursor from the command table and the cached catalog: command names,
+// tool, prompt and resource names, and argument names taken from each
+// tool's input schema. When several candidates remain it extends the word
+// to their common prefix, or lists them if there is none.
+func (sh *shell) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+	head, tail := line[:pos], line[pos:]
+	start := strings.LastIndexAny(head, " \t") + 1
+	partial := head[start:]
+
+	var matches []string
+	for _, c := range sh.candidates(strings.Fields(head[:start]), partial) {
+		if strings.HasPrefix(c, partial) {
+			matches = append(matches, c)
+		}
+	}
+	slices.Sort(matches)
+	matches = slices.Compact(matches)
+
+	switch len(matches) {
+	case 0:
+		return line, pos, true
+	case 1:
+		word := matches[0]
+		if !strings.HasSuffix(word, "=") {
+			word += " "
+		}
+		return head[:start] + word + tail, start + len(word), true
+	}
+	if prefix := commonPrefix(matches); len(prefix) > len(partial) {
+		return head[:start] + prefix + tail, start + len(prefix), true
+	}
+	fmt.Fprintln(sh.w, strings.Join(matches, "  "))
+	return line, pos, true
+}
+
+// candidates returns the words that may follow prev, unfiltered.
+func (sh *shell) candidates(prev []string, partial string) []string {
+	cmd, rest := findCommand(prev)
+	if cmd == nil || !cmd.inShell() {
+		return nextCommandWords(prev)
+	}
+	if cmd.name == "tools call" {
+		return sh.toolCallCandidates(rest, partial)
+	}
+
+	// Walk the arguments typed so far to see where the new word goes.
+	var positional []string
+	afterFlag := ""
+	for _, w := range rest {
+		switch {
+		case afterFlag != "":
+			afterFlag = ""
+		case w == "-arg" || w == "-args":
+			afterFlag = w
+		case strings.HasPrefix(w, "-"):
+		default:
+			positional = append(positional, w)
+		}
+	}
+	takesArgs := cmd.name == "prompts get"
+
+	switch {
+	case afterFlag == "-arg" && takesArgs && len(positional) > 0:
+		return sh.argumentCandidates(cmd.name, positional[0], rest, partial)
+	case afterFlag != "":
+		return nil
+	case strings.HasPrefix(partial, "-") && takesArgs:
+		return []string{"-arg", "-args"}
+	}
+
+	switch cmd.name {
+	case "prompts get":
+		if len(positional) == 0 {
+			return sh.cat.promptNames()
+		}
+	case "resources read":
+		if len(positional) == 0 {
+			return sh.cat.resourceURIs()
+		}
+	case "complete":
+		switch len(positional) {
+		case 0:
+			return []string{"prompt", "resource"}
+		case 1:
+			if positional[0] == "prompt" {
+				return sh.cat.promptNames()
+			}
+			return sh.cat.templateURIs()
+		case 2:
+			if positional[0] == "prompt" {
+				return sh.cat.promptArguments(positional[1])
+			}
+		}
+	}
+	return nil
+}
+
+// toolCallCandidates completes tools call: the tool name, then the flags
+// made from its input schema and the values of enum flags.
+func (sh *shell) toolCallCandidates(rest []string, partial string) []string {
+	if len(rest) == 0 {
+		return sh.cat.toolNames()
+	}
+	tool, ok := sh.cat.tool(rest[0])
+	if !ok {
+		return nil
+	}
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.String("args", "", "")
+	fs.Var(new(argFlags), "arg", "")
+	newSchemaFlags(fs, tool.InputSchema)
+
+	// Find the flags given so far and whether the last one awaits a value.
+	given := make(map[string]bool)
+	var pending *flag.Flag
+	for _, w := range rest[1:] {
+		if pending != nil {
+			pending = nil
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(w, "-"), "=")
+		f := fs.Lookup(name)
+		if !strings.HasPrefix(w, "-") || f == nil {
+			continue
+		}
+		given[name] = true
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !hasValue && !(ok && b.IsBoolFlag()) {
+			pending = f
+		}
+	}
+
+	if pending != nil {
+		switch v := pending.Value.(type) {
+		case *argFlags:
+			return sh.argumentCandidates("tools call", tool.Name, rest, partial)
+		case *schemaFlag:
+			vals := make([]string, len(v.enum))
+			for i, e := range v.enum {
+				vals[i] = fmt.Sprint(e)
+			}
+			return vals
+		}
+		return nil
+	}
+	if !strings.HasPrefix(partial, "-") {
+		return nil
+	}
+	out := []string{"-arg", "-args"}
+	fs.VisitAll(func(f *flag.Flag) {
+		if sf, ok := f.Value.(*schemaFlag); ok && (!given[f.Name] || sf.typ == "array") {
+			out = append(out, "--"+f.Name)
+		}
+	})
+	return out
+}
+
+// argumentCandidates completes the value of an -arg flag: "name=" for every
+// argument not given yet and, once the name is typed, the values of an enum.
+func (sh *shell) argumentCandidates(cmdName, target string, rest []string, partial string) []string {
+	given := make(map[string]bool)
+	for i, w := range rest {
+		if i > 0 && rest[i-1] == "-arg" {
+			name, _, _ := strings.Cut(w, "=")
+			given[name] = true
+		}
+	}
+
+	if cmdName == "prompts get" {
+		var out []string
+		for _, name := range sh.cat.promptArguments(target) {
+			if !given[name] {
+				out = append(out, name+"=")
+			}
+		}
+		return out
+	}
+
+	tool, ok := sh.cat.tool(target)
+	if !ok {
+		return nil
+	}
+	if name, _, ok := strings.Cut(partial, "="); ok {
+		prop, _ := tool.InputSchema.Properties[name].(map[string]any)
+		enum, _ := prop["enum"].([]any)
+		var out []string
+		for _, v := range enum {
+			out = append(out, fmt.Sprintf("%s=%v", name, v))
+		}
+		return out
+	}
+	var out []string
+	for name := range tool.InputSchema.Properties {
+		if !given[name] {
+			out = append(out, name+"=")
+		}
+	}
+	return out
+}
+
+// nextCommandWords returns the words that continue a command name started
+// with prev, plus the shell's own commands at the start of a line.
+func nextCommandWords(prev []string) []string {
+	var out []string
+	if len(prev) == 0 {
+		out = append(out, shellBuiltins...)
+	}
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if !c.inShell() || len(words) <= len(prev) || !slices.Equal(words[:len(prev)], prev) {
+			continue
+		}
+		out = append(out, words[len(prev)])
+	}
+	return out
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
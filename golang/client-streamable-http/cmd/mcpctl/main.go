@@ -0,0 +1,172 @@
+// Command mcpctl talks to any MCP server from the command line. It connects
+// over streamable HTTP, SSE or stdio, runs one subcommand and prints the
+// result as text or JSON:
+//
+//	mcpctl -url http://127.0.0.1:9000/mcp tools list
+//	mcpctl -url http://127.0.0.1:9000/mcp tools call add -arg a=1 -arg b=2
+//	mcpctl -transport stdio -command "./server -stdio" -output json resources list
+//
+// Run mcpctl -h for the connection flags and mcpctl help for the commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/duaraghav8/gomcptest/render"
+	"github.com/duaraghav8/mcp-config"
+)
+
+// options are the connection settings shared by every subcommand.
+type options struct {
+	config.Client
+	Transport string        `flag:"transport" env:"MCP_TRANSPORT" json:"transport" usage:"streamable, sse or stdio (default: sse if the URL path ends in /sse, otherwise streamable)"`
+	Command   string        `flag:"command" env:"MCP_SERVER_COMMAND" json:"command" usage:"command line that starts the server, for the stdio transport"`
+	Output    string        `flag:"output" env:"MCPCTL_OUTPUT" json:"output" usage:"output format: text or json"`
+	Timeout   time.Duration `flag:"timeout" env:"MCPCTL_TIMEOUT" json:"timeout" usage:"how long a command may take, 0 for no limit"`
+	Verbose   bool          `flag:"verbose" env:"MCPCTL_VERBOSE" json:"verbose" usage:"print server notifications and the stderr of stdio servers"`
+	SaveDir   string        `flag:"save-dir" env:"MCPCTL_SAVE_DIR" json:"save_dir" usage:"directory to save images, audio and blobs in, empty to only describe them"`
+	Table     bool          `flag:"table" env:"MCPCTL_TABLE" json:"table" usage:"show structured content as a table where possible"`
+	Follow    bool          `flag:"follow" env:"MCPCTL_FOLLOW" json:"follow" usage:"read the resources that results link to and show them too"`
+	Servers   string        `flag:"servers" env:"MCPCTL_SERVERS" json:"servers" usage:"mcpServers config file for the servers commands"`
+	Keepalive time.Duration `flag:"keepalive" env:"MCPCTL_KEEPALIVE" json:"keepalive" usage:"how often to ping the server to notice a lost connection between commands, 0 to only notice it on the next command"`
+}
+
+// Transport names accepted by -transport.
+const (
+	transportStreamable = "streamable"
+	transportSSE        = "sse"
+	transportStdio      = "stdio"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("mcpctl: ")
+
+	opts := options{
+		Client:    config.Client{URL: "http://127.0.0.1:8080/mcp"},
+		Output:    "text",
+		Timeout:   30 * time.Second,
+		Keepalive: 30 * time.Second,
+		SaveDir:   ".",
+	}
+	var (
+		roots   rootFlags
+		headers headerFlags
+	)
+	flag.Var(&roots, "root", "directory to expose to the server as a root (repeatable)")
+	flag.Var(&headers, "header", "HTTP header to send, as 'Name: value' (repeatable)")
+	flag.Usage = usage
+	if err := config.Register(flag.CommandLine, &opts).Parse(os.Args[1:]); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := opts.validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	args := flag.Args()
+	cmd, rest := findCommand(args)
+	if cmd == nil {
+		if len(args) > 0 && args[0] != "help" {
+			fmt.Fprintf(os.Stderr, "mcpctl: unknown command %q\n\n", strings.Join(args, " "))
+			printCommands(os.Stderr, true)
+			os.Exit(2)
+		}
+		printCommands(os.Stdout, true)
+		return
+	}
+
+	out := newPrinter(os.Stdout, opts.Output == "json", render.Options{Dir: opts.SaveDir, Table: opts.Table}, opts.Follow)
+	if err := run(&opts, roots, headers, cmd, rest, out); err != nil {
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(os.Stderr, "mcpctl: %v\nusage: mcpctl [flags] %s %s\n", usageErr.err, cmd.name, cmd.args)
+			os.Exit(2)
+		}
+		log.Fatal(err)
+	}
+}
+
+// run connects, runs cmd and disconnects. -timeout bounds the handshake and
+// the command, or each step of a long-running command.
+func run(opts *options, roots rootFlags, headers headerFlags, cmd *command, args []string, out *printer) error {
+	if cmd.servers != nil {
+		return runServers(opts, cmd, args, out)
+	}
+	ctx, cancel := withTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	sess, err := connect(ctx, opts, roots, headers)
+	if err != nil {
+		return err
+	}
+	defer sess.close()
+	if cmd.long {
+		ctx = context.Background()
+	}
+	return cmd.run(ctx, sess, args, out)
+}
+
+// withTimeout is context.WithTimeout where 0 means no limit.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+func (o *options) validate() error {
+	var errs []error
+	switch o.Transport {
+	case "", transportStreamable, transportSSE:
+		errs = append(errs, o.Client.Validate())
+	case transportStdio:
+		if strings.TrimSpace(o.Command) == "" {
+			errs = append(errs, errors.New("command: required for the stdio transport"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("transport %q: must be streamable, sse or stdio", o.Transport))
+	}
+	if o.Output != "text" && o.Output != "json" {
+		errs = append(errs, fmt.Errorf("output %q: must be text or json", o.Output))
+	}
+	if o.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("timeout %s: must not be negative", o.Timeout))
+	}
+	return errors.Join(errs...)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: mcpctl [flags] <command> [args]\n\nflags:\n")
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr)
+	printCommands(os.Stderr, true)
+}
+
+// headerFlags collects repeated -header flags.
+type headerFlags map[string]string
+
+func (h *headerFlags) String() string {
+	var parts []string
+	for k, v := range *h {
+		parts = append(parts, k+": "+v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (h *headerFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return errors.New("want 'Name: value'")
+	}
+	if *h == nil {
+		*h = make(headerFlags)
+	}
+	(*h)[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/duaraghav8/gomcptest/render"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// printer writes command results either for people or, with -output json,
+// as the server's result object.
+type printer struct {
+	w    io.Writer
+	json bool
+	// render and follow configure how content is shown in text mode.
+	render render.Options
+	follow bool
+}
+
+func newPrinter(w io.Writer, asJSON bool, opts render.Options, follow bool) *printer {
+	return &printer{w: w, json: asJSON, render: opts, follow: follow}
+}
+
+// renderer returns a content renderer writing where p does. With -follow
+// it reads linked resources through c.
+func (p *printer) renderer(c *client.Client) *render.Renderer {
+	opts := p.render
+	if p.follow {
+		opts.Follow = func(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+			req := mcp.ReadResourceRequest{}
+			req.Params.URI = uri
+			return c.ReadResource(ctx, req)
+		}
+	}
+	return render.New(p.w, opts)
+}
+
+// print writes v as indented JSON in JSON mode and calls text otherwise.
+func (p *printer) print(v any, text func(w io.Writer)) error {
+	if p.json {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	text(p.w)
+	return nil
+}
+
+// table writes aligned columns; the first row is the header.
+func table(w io.Writer, rows [][]string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
+
+// oneLine shortens a description to its first line for table cells.
+func oneLine(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	return s
+}
+
+// schemaArgs summarizes a tool's input schema as "a:number* b:string",
+// where * marks required arguments.
+func schemaArgs(schema mcp.ToolInputSchema) string {
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		part := name
+		if prop, ok := schema.Properties[name].(map[string]any); ok {
+			if t, ok := prop["type"].(string); ok {
+				part += ":" + t
+			}
+		}
+		if slices.Contains(schema.Required, name) {
+			part += "*"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
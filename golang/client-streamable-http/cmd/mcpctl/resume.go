@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	headerSessionID   = "Mcp-Session-Id"
+	headerLastEventID = "Last-Event-ID"
+)
+
+// resumingTransport remembers the ID of the last event received on each
+// session's GET stream. The mcp-go transport reopens that stream on its own
+// when it drops; this RoundTripper adds Last-Event-ID to the new request so
+// the server can resend what was missed in between.
+type resumingTransport struct {
+	base http.RoundTripper
+
+	mu     sync.Mutex
+	lastID map[string]string // session ID -> last event ID
+}
+
+func newResumingTransport(base http.RoundTripper) *resumingTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &resumingTransport{base: base, lastID: make(map[string]string)}
+}
+
+func (t *resumingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sessionID := req.Header.Get(headerSessionID)
+	if req.Method != http.MethodGet || sessionID == "" {
+		return t.base.RoundTrip(req)
+	}
+
+	t.mu.Lock()
+	last := t.lastID[sessionID]
+	t.mu.Unlock()
+	if last != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(headerLastEventID, last)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	resp.Body = &eventIDReader{
+		ReadCloser: resp.Body,
+		onEvent: func(id string) {
+			t.mu.Lock()
+			t.lastID[sessionID] = id
+			t.mu.Unlock()
+		},
+	}
+	return resp, nil
+}
+
+// eventIDReader passes an SSE body through unchanged and reports the id of
+// every event once the event is complete.
+type eventIDReader struct {
+	io.ReadCloser
+	onEvent func(id string)
+
+	line    []byte // current, unterminated line
+	pending string // id of the event being read
+}
+
+func (r *eventIDReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	for _, c := range p[:n] {
+		if c != '\n' {
+			r.line = append(r.line, c)
+			continue
+		}
+		line := string(bytes.TrimRight(r.line, "\r"))
+		r.line = r.line[:0]
+		switch {
+		case line == "":
+			if r.pending != "" {
+				r.onEvent(r.pending)
+				r.pending = ""
+			}
+		case strings.HasPrefix(line, "id:"):
+			r.pending = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// rootFlags collects repeated -root flags.
+type rootFlags []string
+
+func (r *rootFlags) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *rootFlags) Set(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	*r = append(*r, abs)
+	return nil
+}
+
+// dirRoots answers roots/list with the directories given on the command line.
+// Only directories that currently exist are advertised, so creating or
+// removing one of them changes the list the server sees.
+type dirRoots struct {
+	dirs []string
+
+	mu    sync.RWMutex
+	roots []mcp.Root
+}
+
+func newDirRoots(dirs []string) *dirRoots {
+	d := &dirRoots{dirs: dirs}
+	d.refresh()
+	return d
+}
+
+func (d *dirRoots) ListRoots(ctx context.Context, request mcp.ListRootsRequest) (*mcp.ListRootsResult, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return &mcp.ListRootsResult{Roots: slices.Clone(d.roots)}, nil
+}
+
+// refresh re-checks the configured directories and reports whether the set
+// of advertised roots changed.
+func (d *dirRoots) refresh() bool {
+	var roots []mcp.Root
+	for _, dir := range d.dirs {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		u := url.URL{Scheme: "file", Path: filepath.ToSlash(dir)}
+		roots = append(roots, mcp.Root{URI: u.String(), Name: filepath.Base(dir)})
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	changed := !slices.Equal(d.roots, roots)
+	d.roots = roots
+	return changed
+}
+
+// watch polls the configured directories and sends
+// notifications/roots/list_changed whenever the advertised set changes.
+func (d *dirRoots) watch(ctx context.Context, c *client.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !d.refresh() {
+				continue
+			}
+			log.Println("roots changed, notifying server")
+			if err := c.RootListChanges(ctx); err != nil {
+				log.Printf("Failed to send roots list changed: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
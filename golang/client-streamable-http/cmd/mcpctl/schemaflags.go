@@ -0,0 +1,343 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// schemaFlags turns a tool's input schema into command-line flags: --a 25
+// for a number, --sender.name Ann for a field of a nested object, --tag x
+// --tag y for an array, and so on. Values are checked against the schema's
+// type and enum as they are parsed.
+type schemaFlags struct {
+	flags []*schemaFlag
+	// required holds the dotted paths of required properties. Those inside
+	// an optional object are only required when the object is given.
+	required map[string]bool
+	// objects holds the dotted paths of objects expanded into one flag per
+	// field.
+	objects map[string]bool
+	order   []string // required, in schema order
+}
+
+// schemaFlag is one leaf of the schema.
+type schemaFlag struct {
+	path   []string
+	typ    string // JSON Schema type; "" accepts any JSON value or a string
+	items  string // item type of an array
+	enum   []any  // allowed values, of the items for an array
+	values []any  // parsed values; more than one only for arrays
+}
+
+// reservedFlags are the fixed flags of tools call. Properties with these
+// names are only reachable through -arg and -args.
+var reservedFlags = []string{"arg", "args", "h", "help"}
+
+// newSchemaFlags defines a flag on fs for every property of schema.
+func newSchemaFlags(fs *flag.FlagSet, schema mcp.ToolInputSchema) *schemaFlags {
+	sf := &schemaFlags{required: make(map[string]bool), objects: make(map[string]bool)}
+	sf.add(fs, nil, schema.Properties, schema.Required)
+	return sf
+}
+
+func (sf *schemaFlags) add(fs *flag.FlagSet, prefix []string, props map[string]any, required []string) {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		path := append(slices.Clone(prefix), name)
+		dotted := strings.Join(path, ".")
+		if slices.Contains(required, name) {
+			sf.required[dotted] = true
+			sf.order = append(sf.order, dotted)
+		}
+		prop, _ := props[name].(map[string]any)
+		typ, _ := prop["type"].(string)
+		if sub, ok := prop["properties"].(map[string]any); ok && typ == "object" && len(sub) > 0 {
+			sf.objects[dotted] = true
+			subRequired, _ := prop["required"].([]any)
+			sf.add(fs, path, sub, stringList(subRequired))
+			continue
+		}
+		if len(path) == 1 && slices.Contains(reservedFlags, name) {
+			continue
+		}
+		f := &schemaFlag{path: path, typ: typ}
+		f.enum, _ = prop["enum"].([]any)
+		if items, ok := prop["items"].(map[string]any); ok && typ == "array" {
+			f.items, _ = items["type"].(string)
+			f.enum, _ = items["enum"].([]any)
+		}
+		sf.flags = append(sf.flags, f)
+		fs.Var(f, dotted, f.usage(prop, slices.Contains(required, name)))
+	}
+}
+
+func stringList(vs []any) []string {
+	out := make([]string, 0, len(vs))
+	for _, v := range vs {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (f *schemaFlag) usage(prop map[string]any, required bool) string {
+	var parts []string
+	if d, ok := prop["description"].(string); ok && d != "" {
+		parts = append(parts, oneLine(d))
+	}
+	if len(f.enum) > 0 {
+		vals := make([]string, len(f.enum))
+		for i, v := range f.enum {
+			vals[i] = fmt.Sprint(v)
+		}
+		parts = append(parts, "one of "+strings.Join(vals, ", "))
+	}
+	if f.typ == "array" {
+		parts = append(parts, "repeatable")
+	}
+	if required {
+		parts = append(parts, "required")
+	}
+	// flag.PrintDefaults shows a back-quoted word as the value name.
+	if name := f.valueName(); name != "" {
+		parts = append(parts, "`"+name+"`")
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (f *schemaFlag) valueName() string {
+	switch f.typ {
+	case "boolean":
+		return ""
+	case "array":
+		if f.items != "" {
+			return f.items
+		}
+		return "value"
+	case "":
+		return "value"
+	}
+	return f.typ
+}
+
+func (f *schemaFlag) String() string {
+	if f == nil || len(f.values) == 0 {
+		return ""
+	}
+	return fmt.Sprint(f.values...)
+}
+
+// IsBoolFlag lets boolean properties be given as a bare --name.
+func (f *schemaFlag) IsBoolFlag() bool { return f.typ == "boolean" }
+
+func (f *schemaFlag) Set(s string) error {
+	if f.typ == "array" {
+		// A JSON array replaces what was given so far; anything else is
+		// one more item.
+		if strings.HasPrefix(strings.TrimSpace(s), "[") {
+			var items []any
+			if err := json.Unmarshal([]byte(s), &items); err != nil {
+				return err
+			}
+			for i, v := range items {
+				if err := checkType(f.items, v); err != nil {
+					return fmt.Errorf("item %d: %w", i+1, err)
+				}
+				if err := f.checkEnum(v); err != nil {
+					return fmt.Errorf("item %d: %w", i+1, err)
+				}
+			}
+			f.values = items
+			return nil
+		}
+		v, err := parseTyped(f.items, s)
+		if err != nil {
+			return err
+		}
+		if err := f.checkEnum(v); err != nil {
+			return err
+		}
+		f.values = append(f.values, v)
+		return nil
+	}
+	v, err := parseTyped(f.typ, s)
+	if err != nil {
+		return err
+	}
+	if err := f.checkEnum(v); err != nil {
+		return err
+	}
+	f.values = []any{v}
+	return nil
+}
+
+func (f *schemaFlag) checkEnum(v any) error {
+	if len(f.enum) == 0 {
+		return nil
+	}
+	for _, e := range f.enum {
+		if fmt.Sprint(e) == fmt.Sprint(v) {
+			return nil
+		}
+	}
+	vals := make([]string, len(f.enum))
+	for i, e := range f.enum {
+		vals[i] = fmt.Sprint(e)
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(vals, ", "))
+}
+
+// parseTyped parses a flag value as the given JSON Schema type.
+func parseTyped(typ, s string) (any, error) {
+	switch typ {
+	case "string":
+		return s, nil
+	case "integer":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		return n, nil
+	case "number":
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return n, nil
+	case "boolean":
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	case "object", "array":
+		var v any
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return nil, fmt.Errorf("must be a JSON %s: %v", typ, err)
+		}
+		return v, nil
+	}
+	// Untyped: JSON if it parses, a string otherwise, as with -arg.
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return s, nil
+	}
+	return v, nil
+}
+
+// checkType checks a value decoded from JSON against the given JSON Schema
+// type, as parseTyped does for a flag value.
+func checkType(typ string, v any) error {
+	ok := true
+	switch typ {
+	case "string":
+		_, ok = v.(string)
+	case "integer":
+		n, isNumber := v.(float64)
+		ok = isNumber && n == math.Trunc(n)
+	case "number":
+		_, ok = v.(float64)
+	case "boolean":
+		_, ok = v.(bool)
+	case "object":
+		_, ok = v.(map[string]any)
+	case "array":
+		_, ok = v.([]any)
+	}
+	if !ok {
+		return fmt.Errorf("must be of type %s", typ)
+	}
+	return nil
+}
+
+// apply writes the values of the flags that were given into args, creating
+// nested objects as needed, and checks that every required property is
+// there.
+func (sf *schemaFlags) apply(args map[string]any) error {
+	for _, f := range sf.flags {
+		if len(f.values) == 0 {
+			continue
+		}
+		obj := args
+		for _, key := range f.path[:len(f.path)-1] {
+			next, ok := obj[key].(map[string]any)
+			if !ok {
+				next = make(map[string]any)
+				obj[key] = next
+			}
+			obj = next
+		}
+		var v any = f.values[0]
+		if f.typ == "array" {
+			v = f.values
+		}
+		obj[f.path[len(f.path)-1]] = v
+	}
+
+	var missing []string
+	for _, dotted := range sf.order {
+		if sf.missing(args, strings.Split(dotted, ".")) {
+			missing = append(missing, dotted)
+		}
+	}
+	var flags []string
+	for _, dotted := range missing {
+		if !sf.objects[dotted] {
+			flags = append(flags, "--"+dotted)
+			continue
+		}
+		// An object is reported through its required fields, if it has
+		// any, and otherwise as a whole.
+		if !slices.ContainsFunc(missing, func(m string) bool { return strings.HasPrefix(m, dotted+".") }) {
+			flags = append(flags, "--"+dotted+".*")
+		}
+	}
+	if len(flags) > 0 {
+		return usagef("missing required flag(s) %s", strings.Join(flags, ", "))
+	}
+	return nil
+}
+
+// missing reports whether the required property at path is absent from
+// args. A property inside an absent object only counts as missing if that
+// object, and every one between it and the property, is required as well.
+func (sf *schemaFlags) missing(args map[string]any, path []string) bool {
+	var v any = args
+	for i, key := range path {
+		obj, ok := v.(map[string]any)
+		if ok {
+			v, ok = obj[key]
+		}
+		if !ok {
+			for j := i; j < len(path); j++ {
+				if !sf.required[strings.Join(path[:j+1], ".")] {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// flagNames returns the flag name of every leaf property, for completion.
+func (sf *schemaFlags) flagNames() []string {
+	names := make([]string, 0, len(sf.flags))
+	for _, f := range sf.flags {
+		names = append(names, strings.Join(f.path, "."))
+	}
+	return names
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/duaraghav8/gomcptest/manager"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// runServers connects to every server in the -servers config file, runs a
+// servers command against them all and disconnects.
+func runServers(opts *options, cmd *command, args []string, out *printer) error {
+	if opts.Servers == "" {
+		return usagef("-servers must name an mcpServers config file")
+	}
+	cfg, err := manager.LoadConfig(opts.Servers)
+	if err != nil {
+		return err
+	}
+	var stderr io.Writer
+	if opts.Verbose {
+		stderr = os.Stderr
+	}
+	logger := &transportLogger{verbose: opts.Verbose}
+	m := manager.New(cfg, manager.Options{
+		ClientInfo: mcp.Implementation{Name: "mcpctl", Version: "0.1.0"},
+		Logger:     logger,
+		Stderr:     stderr,
+		OnNotification: func(server string, n mcp.JSONRPCNotification) {
+			if opts.Verbose {
+				fmt.Fprintf(os.Stderr, "notification from %s: %s\n", server, formatNotification(n))
+			}
+		},
+	})
+	defer closeManager(m, logger)
+
+	ctx, cancel := withTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	m.Start(ctx)
+	return cmd.servers(ctx, m, args, out)
+}
+
+// closeManager disconnects, quietly as session.close does.
+func closeManager(m *manager.Manager, logger *transportLogger) {
+	logger.closed.Store(true)
+	var exitErr *exec.ExitError
+	if err := m.Close(); err != nil && !errors.As(err, &exitErr) {
+		log.Printf("close: %v", err)
+	}
+}
+
+func runServersStatus(ctx context.Context, m *manager.Manager, args []string, out *printer) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	health := m.Health(ctx)
+	return out.print(health, func(w io.Writer) {
+		rows := [][]string{{"NAME", "TRANSPORT", "STATUS", "SERVER", "TOOLS", "LATENCY", "ERROR"}}
+		for _, h := range health {
+			server, latency := "", ""
+			if h.Server != nil {
+				server = strings.TrimSpace(h.Server.Name + " " + h.Server.Version)
+			}
+			if h.Latency > 0 {
+				latency = h.Latency.Round(time.Microsecond).String()
+			}
+			rows = append(rows, []string{h.Name, h.Transport, string(h.Status), server, fmt.Sprint(h.Tools), latency, oneLine(h.Error)})
+		}
+		table(w, rows)
+	})
+}
+
+func runServersTools(ctx context.Context, m *manager.Manager, args []string, out *printer) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	tools := m.Tools()
+	return out.print(map[string]any{"tools": tools}, func(w io.Writer) {
+		rows := [][]string{{"NAME", "ARGUMENTS", "DESCRIPTION"}}
+		for _, t := range tools {
+			rows = append(rows, []string{t.Name, schemaArgs(t.InputSchema), oneLine(t.Description)})
+		}
+		table(w, rows)
+	})
+}
+
+func runServersCall(ctx context.Context, m *manager.Manager, args []string, out *printer) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return usagef("want a tool name, as server%stool, first", manager.Separator)
+	}
+	name := args[0]
+	tool, ok := m.Tool(name)
+	if !ok {
+		// Let the manager say why: an unknown or unavailable server, or a
+		// tool that is not enabled.
+		tool = mcp.Tool{Name: name}
+	}
+	arguments, err := toolArguments("servers call", tool, args[1:], out.w)
+	if err != nil || arguments == nil {
+		return err
+	}
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = arguments
+	res, err := m.CallTool(ctx, req)
+	if err != nil {
+		return fmt.Errorf("call %s: %w", name, err)
+	}
+	server, _, _ := strings.Cut(name, manager.Separator)
+	c, _ := m.Client(server)
+	return printToolResult(ctx, out, c, res)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/duaraghav8/gomcptest/reconnect"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// session is an initialized connection to a server.
+type session struct {
+	c      *client.Client
+	server *mcp.InitializeResult
+	// timeout bounds each request made on behalf of a long command.
+	timeout time.Duration
+	// cancel stops the transport's background work: the stdio server
+	// process, the SSE stream and the roots watcher.
+	cancel context.CancelFunc
+
+	logger *transportLogger
+
+	mu          sync.Mutex
+	notify      func(mcp.JSONRPCNotification) // nil to ignore notifications
+	reconnected func()
+}
+
+// connect starts a client on the configured transport and initializes it.
+// ctx bounds the handshake only; the connection lasts until close.
+func connect(ctx context.Context, opts *options, roots rootFlags, headers headerFlags) (*session, error) {
+	var clientOpts []client.ClientOption
+	var dirs *dirRoots
+	if len(roots) > 0 {
+		dirs = newDirRoots(roots)
+		clientOpts = append(clientOpts, client.WithRootsHandler(dirs))
+	}
+
+	logger := &transportLogger{verbose: opts.Verbose}
+	s := &session{timeout: opts.Timeout, logger: logger}
+	// A new connection is made, initialized with the same parameters and
+	// subscribed to the same resources whenever the server goes away or
+	// forgets the session.
+	trans := reconnect.New(dialer(opts, headers, logger), reconnect.Options{
+		PingInterval:  opts.Keepalive,
+		OnStateChange: s.stateChanged,
+	})
+	c := client.NewClient(trans, clientOpts...)
+	s.c = c
+	if opts.Verbose {
+		s.notify = func(n mcp.JSONRPCNotification) {
+			fmt.Fprintf(os.Stderr, "notification: %s\n", formatNotification(n))
+		}
+	}
+	c.OnNotification(s.dispatch)
+
+	bg, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	if err := c.Start(bg); err != nil {
+		cancel()
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+
+	req := mcp.InitializeRequest{}
+	req.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	req.Params.ClientInfo = mcp.Implementation{Name: "mcpctl", Version: "0.1.0"}
+	var err error
+	s.server, err = c.Initialize(ctx, req)
+	if err != nil {
+		c.Close()
+		cancel()
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	if dirs != nil {
+		go dirs.watch(bg, c, 2*time.Second)
+	}
+	return s, nil
+}
+
+// dialer returns a function that opens and starts a new connection on the
+// configured transport.
+func dialer(opts *options, headers headerFlags, logger *transportLogger) reconnect.Dialer {
+	switch opts.transport() {
+	case transportStdio:
+		fields := strings.Fields(opts.Command)
+		return func(ctx context.Context) (transport.Interface, error) {
+			stdio := transport.NewStdioWithOptions(fields[0], nil, fields[1:], transport.WithCommandLogger(logger))
+			if err := stdio.Start(ctx); err != nil {
+				return nil, err
+			}
+			// The server blocks once the pipe fills, so drain it either way.
+			dst := io.Discard
+			if opts.Verbose {
+				dst = os.Stderr
+			}
+			go io.Copy(dst, stdio.Stderr())
+			return stdio, nil
+		}
+	case transportSSE:
+		return func(ctx context.Context) (transport.Interface, error) {
+			sse, err := transport.NewSSE(opts.URL, transport.WithHeaders(headers), transport.WithSSELogger(logger))
+			if err != nil {
+				return nil, err
+			}
+			if err := sse.Start(ctx); err != nil {
+				return nil, err
+			}
+			return sse, nil
+		}
+	}
+	// Keep the standalone GET stream open for server notifications and roots
+	// requests. The transport reopens it when it drops, and resumingTransport
+	// asks the server to replay what was sent in the meantime.
+	httpClient := &http.Client{Transport: newResumingTransport(nil)}
+	return func(ctx context.Context) (transport.Interface, error) {
+		trans, err := transport.NewStreamableHTTP(
+			opts.URL,
+			transport.WithContinuousListening(),
+			transport.WithHTTPHeaders(headers),
+			transport.WithHTTPLogger(logger),
+			transport.WithHTTPBasicClient(httpClient),
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := trans.Start(ctx); err != nil {
+			return nil, err
+		}
+		return trans, nil
+	}
+}
+
+// stateChanged reports connection losses and recoveries.
+func (s *session) stateChanged(state reconnect.State, err error) {
+	switch state {
+	case reconnect.Reconnecting:
+		log.Printf("connection lost (%v), reconnecting", err)
+	case reconnect.Disconnected:
+		log.Printf("could not reconnect: %v", err)
+	case reconnect.Connected:
+		if err != nil {
+			log.Printf("reconnected, but: %v", err)
+		} else {
+			log.Printf("reconnected")
+		}
+		s.mu.Lock()
+		fn := s.reconnected
+		s.mu.Unlock()
+		if fn != nil {
+			fn()
+		}
+	}
+}
+
+// onReconnect sets a function to call after each reconnection.
+func (s *session) onReconnect(fn func()) {
+	s.mu.Lock()
+	s.reconnected = fn
+	s.mu.Unlock()
+}
+
+// tool looks up a tool by name. A tool the server does not list is returned
+// with an empty schema, so that calling it still reaches the server and
+// fails there.
+func (s *session) tool(ctx context.Context, name string) (mcp.Tool, error) {
+	if s.server.Capabilities.Tools == nil {
+		return mcp.Tool{Name: name}, nil
+	}
+	res, err := s.c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return mcp.Tool{}, fmt.Errorf("list tools: %w", err)
+	}
+	for _, t := range res.Tools {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return mcp.Tool{Name: name}, nil
+}
+
+// onNotification replaces the handler for server notifications.
+func (s *session) onNotification(fn func(mcp.JSONRPCNotification)) {
+	s.mu.Lock()
+	s.notify = fn
+	s.mu.Unlock()
+}
+
+func (s *session) dispatch(n mcp.JSONRPCNotification) {
+	s.mu.Lock()
+	fn := s.notify
+	s.mu.Unlock()
+	if fn != nil {
+		fn(n)
+	}
+}
+
+// formatNotification renders a notification as its method and JSON params.
+func formatNotification(n mcp.JSONRPCNotification) string {
+	fields := maps.Clone(n.Params.AdditionalFields)
+	if len(n.Params.Meta) > 0 {
+		if fields == nil {
+			fields = make(map[string]any)
+		}
+		fields["_meta"] = n.Params.Meta
+	}
+	if len(fields) == 0 {
+		return n.Method
+	}
+	params, _ := json.Marshal(fields)
+	return n.Method + " " + string(params)
+}
+
+func (s *session) close() {
+	// The transport's listener fails as the connection goes away; that is
+	// expected now.
+	s.logger.closed.Store(true)
+	// A stdio server often dies of SIGPIPE logging its shutdown to the stderr
+	// pipe the transport has just closed; how it exits is not our concern.
+	var exitErr *exec.ExitError
+	if err := s.c.Close(); err != nil && !errors.As(err, &exitErr) {
+		log.Printf("close: %v", err)
+	}
+	s.cancel()
+}
+
+// transport returns the transport to use, guessing it from the URL when
+// -transport is not set.
+func (o *options) transport() string {
+	if o.Transport != "" {
+		return o.Transport
+	}
+	if u, err := url.Parse(o.URL); err == nil && strings.HasSuffix(u.Path, "/sse") {
+		return transportSSE
+	}
+	return transportStreamable
+}
+
+// transportLogger shows the transports' informational messages only with
+// -verbose, so they do not clutter command output, and drops their errors
+// once the session is closing.
+type transportLogger struct {
+	verbose bool
+	closed  atomic.Bool
+}
+
+func (l *transportLogger) Infof(format string, v ...any) {
+	if l.verbose && !l.closed.Load() {
+		log.Printf(format, v...)
+	}
+}
+
+func (l *transportLogger) Errorf(format string, v ...any) {
+	if !l.closed.Load() {
+		log.Printf(format, v...)
+	}
+}
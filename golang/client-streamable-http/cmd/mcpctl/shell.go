@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"golang.org/x/term"
+)
+
+// shell is an interactive session: it reads commands from the user, runs
+// them on one connection and prints notifications as they arrive.
+type shell struct {
+	s   *session
+	cat *catalog
+	out *printer
+	// w is where everything is written: the line editor when stdin is a
+	// terminal, so that output appears above the prompt, or stdout.
+	w    io.Writer
+	term *term.Terminal
+}
+
+// Words the shell understands besides the commands.
+var shellBuiltins = []string{"help", "refresh", "exit", "quit"}
+
+const shellPrompt = "mcp> "
+
+func runShell(ctx context.Context, s *session, args []string, out *printer) error {
+	fs := flag.NewFlagSet("shell", flag.ContinueOnError)
+	historyPath := fs.String("history", defaultHistoryPath(), "file to keep command history in, empty for none")
+	if pos, err := parseFlags(fs, args); err != nil {
+		return err
+	} else if err := noArgs(pos); err != nil {
+		return err
+	}
+
+	sh := &shell{s: s, cat: newCatalog(s), w: os.Stdout}
+	stdin := int(os.Stdin.Fd())
+	if term.IsTerminal(stdin) {
+		state, err := term.MakeRaw(stdin)
+		if err != nil {
+			return fmt.Errorf("shell: %w", err)
+		}
+		defer term.Restore(stdin, state)
+
+		sh.term = term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout}, shellPrompt)
+		if w, h, err := term.GetSize(stdin); err == nil {
+			sh.term.SetSize(w, h)
+		}
+		if *historyPath != "" {
+			hist, err := loadHistory(*historyPath, 500)
+			if err != nil {
+				log.Printf("history: %v", err)
+			} else {
+				sh.term.History = hist
+			}
+		}
+		sh.term.AutoCompleteCallback = sh.complete
+		sh.w = sh.term
+		// Raw mode needs \r\n, which the line editor adds.
+		log.SetOutput(sh.term)
+		defer log.SetOutput(os.Stderr)
+	}
+	sh.out = newPrinter(sh.w, out.json, out.render, out.follow)
+
+	rctx, cancel := withTimeout(ctx, s.timeout)
+	if err := sh.cat.refreshAll(rctx); err != nil {
+		fmt.Fprintf(sh.w, "warning: %v\n", err)
+	}
+	cancel()
+	s.onNotification(sh.notification)
+	defer s.onNotification(nil)
+	s.onReconnect(sh.reconnected)
+	defer s.onReconnect(nil)
+
+	info := s.server.ServerInfo
+	fmt.Fprintf(sh.w, "Connected to %s %s. Type help for commands, Tab to complete, exit to leave.\n", info.Name, info.Version)
+
+	read := sh.readLine()
+	for {
+		line, err := read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if sh.exec(ctx, line) {
+			return nil
+		}
+	}
+}
+
+// readLine returns a function that reads the next line of input, through the
+// line editor or, when stdin is not a terminal, from a plain scanner.
+func (sh *shell) readLine() func() (string, error) {
+	if sh.term != nil {
+		return sh.term.ReadLine
+	}
+	sc := bufio.NewScanner(os.Stdin)
+	return func() (string, error) {
+		if !sc.Scan() {
+			if err := sc.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+		return sc.Text(), nil
+	}
+}
+
+// exec runs one line of input and reports whether the user asked to leave.
+func (sh *shell) exec(ctx context.Context, line string) (quit bool) {
+	words, err := splitWords(line)
+	if err != nil {
+		fmt.Fprintf(sh.w, "error: %v\n", err)
+		return false
+	}
+	if len(words) == 0 {
+		return false
+	}
+	switch words[0] {
+	case "exit", "quit":
+		return true
+	case "help":
+		printCommands(sh.w, false)
+		fmt.Fprintf(sh.w, "  %s\n", strings.Join(shellBuiltins, ", "))
+		return false
+	case "refresh":
+		rctx, cancel := withTimeout(ctx, sh.s.timeout)
+		defer cancel()
+		if err := sh.cat.refreshAll(rctx); err != nil {
+			fmt.Fprintf(sh.w, "error: %v\n", err)
+		}
+		return false
+	}
+
+	cmd, rest := findCommand(words)
+	if cmd == nil || !cmd.inShell() {
+		fmt.Fprintf(sh.w, "unknown command %q, type help for a list\n", strings.Join(words, " "))
+		return false
+	}
+	cctx, cancel := withTimeout(ctx, sh.s.timeout)
+	defer cancel()
+	if err := cmd.run(cctx, sh.s, rest, sh.out); err != nil {
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(sh.w, "%v\nusage: %s %s\n", usageErr.err, cmd.name, cmd.args)
+		} else {
+			fmt.Fprintf(sh.w, "error: %v\n", err)
+		}
+	}
+	return false
+}
+
+// notification prints a server notification above the prompt and reloads
+// the cached list it says has changed.
+func (sh *shell) notification(n mcp.JSONRPCNotification) {
+	fmt.Fprintf(sh.w, "<- %s\n", formatNotification(n))
+	kind, ok := listChangedKinds[n.Method]
+	if !ok {
+		return
+	}
+	go func() {
+		ctx, cancel := withTimeout(context.Background(), sh.s.timeout)
+		defer cancel()
+		count, err := sh.cat.refresh(ctx, kind)
+		if err != nil {
+			fmt.Fprintf(sh.w, "error: %v\n", err)
+			return
+		}
+		fmt.Fprintf(sh.w, "%s reloaded, %d available\n", kind, count)
+	}()
+}
+
+// reconnected reloads every cached list, since a restarted server may offer
+// different tools, resources and prompts.
+func (sh *shell) reconnected() {
+	go func() {
+		ctx, cancel := withTimeout(context.Background(), sh.s.timeout)
+		defer cancel()
+		if err := sh.cat.refreshAll(ctx); err != nil {
+			fmt.Fprintf(sh.w, "error: %v\n", err)
+		}
+	}()
+}
+
+// splitWords splits a line into words the way a POSIX shell would, minus
+// expansions: single quotes keep everything literally, double quotes allow
+// backslash escapes, and a backslash outside quotes escapes any character.
+func splitWords(line string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// fileHistory is the line editor's history, appended to a file so that it
+// carries over between shells.
+type fileHistory struct {
+	path    string
+	max     int
+	entries []string // oldest first
+}
+
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".mcpctl_history")
+}
+
+// loadHistory reads the last max lines of the history file at path, if it
+// exists.
+func loadHistory(path string, max int) (*fileHistory, error) {
+	h := &fileHistory{path: path, max: max}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if over := len(h.entries) - max; over > 0 {
+		// Trim the file too, so it does not grow forever.
+		h.entries = h.entries[over:]
+		if err := os.WriteFile(path, []byte(strings.Join(h.entries, "\n")+"\n"), 0o600); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+func (h *fileHistory) Add(entry string) {
+	if strings.TrimSpace(entry) == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry) {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > h.max {
+		h.entries = h.entries[1:]
+	}
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		log.Printf("history: %v", err)
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, entry)
+}
+
+func (h *fileHistory) Len() int { return len(h.entries) }
+
+func (h *fileHistory) At(idx int) string { return h.entries[len(h.entries)-1-idx] }
@@ -0,0 +1,212 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/duaraghav8/mcp-config"
+)
+
+// Transport names accepted in ServerConfig.Transport.
+const (
+	TransportStreamable = "streamable"
+	TransportSSE        = "sse"
+	TransportStdio      = "stdio"
+)
+
+// Config is an mcpServers config file, the format MCP hosts share:
+//
+//	{
+//	  "mcpServers": {
+//	    "math": {"url": "http://127.0.0.1:8080/mcp"},
+//	    "local": {"command": "python", "args": ["server.py"]},
+//	    "hf": {
+//	      "url": "https://huggingface.co/mcp",
+//	      "headers": {"Authorization": "Bearer ${HF_TOKEN}"},
+//	      "enabledTools": ["hf_whoami", "space_search"]
+//	    }
+//	  }
+//	}
+//
+// Other top-level keys are ignored, so a host's own settings may sit beside
+// mcpServers.
+type Config struct {
+	Servers map[string]ServerConfig `json:"mcpServers"`
+}
+
+// ServerConfig is one entry of mcpServers. ${VAR} in its strings is
+// replaced by the environment variable, which keeps secrets out of the file.
+type ServerConfig struct {
+	// URL is where an HTTP server listens. Exactly one of URL and Command
+	// is set.
+	URL string `json:"url,omitempty"`
+	// Command, Args and Env start a stdio server. Env is added to this
+	// process's environment.
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	// Transport is streamable, sse or stdio. When empty it is stdio for a
+	// command, sse for a URL whose path ends in /sse and streamable
+	// otherwise.
+	Transport string `json:"transport,omitempty"`
+	// Headers are sent with every HTTP request.
+	Headers map[string]string `json:"headers,omitempty"`
+	// OAuth, if set, authorizes HTTP requests with OAuth tokens.
+	OAuth *OAuthConfig `json:"oauth,omitempty"`
+	// EnabledTools, if set, limits the tools offered to those named.
+	EnabledTools []string `json:"enabledTools,omitempty"`
+	// Disabled skips the server without removing its entry.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// OAuthConfig holds a server's OAuth client settings. Without a client ID
+// the client registers itself dynamically.
+type OAuthConfig struct {
+	ClientID              string   `json:"clientId,omitempty"`
+	ClientSecret          string   `json:"clientSecret,omitempty"`
+	RedirectURI           string   `json:"redirectUri,omitempty"`
+	Scopes                []string `json:"scopes,omitempty"`
+	AuthServerMetadataURL string   `json:"authServerMetadataUrl,omitempty"`
+}
+
+// serverName keeps names usable as tool name prefixes.
+var serverName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// LoadConfig reads and checks a config file. Unknown keys within a server
+// entry are rejected to catch typos.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	var file struct {
+		Servers map[string]json.RawMessage `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	cfg := &Config{Servers: make(map[string]ServerConfig, len(file.Servers))}
+	for name, raw := range file.Servers {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		var sc ServerConfig
+		if err := dec.Decode(&sc); err != nil {
+			return nil, fmt.Errorf("config file %s: %s: %w", path, name, err)
+		}
+		cfg.Servers[name] = sc.expand()
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config file %s:\n%w", path, err)
+	}
+	return cfg, nil
+}
+
+// expand replaces ${VAR} references with environment variables.
+func (sc ServerConfig) expand() ServerConfig {
+	expandMap := func(m map[string]string) map[string]string {
+		out := make(map[string]string, len(m))
+		for k, v := range m {
+			out[k] = os.ExpandEnv(v)
+		}
+		return out
+	}
+	sc.URL = os.ExpandEnv(sc.URL)
+	sc.Command = os.ExpandEnv(sc.Command)
+	args := make([]string, len(sc.Args))
+	for i, a := range sc.Args {
+		args[i] = os.ExpandEnv(a)
+	}
+	sc.Args = args
+	sc.Env = expandMap(sc.Env)
+	sc.Headers = expandMap(sc.Headers)
+	if sc.OAuth != nil {
+		oauth := *sc.OAuth
+		oauth.ClientID = os.ExpandEnv(oauth.ClientID)
+		oauth.ClientSecret = os.ExpandEnv(oauth.ClientSecret)
+		sc.OAuth = &oauth
+	}
+	return sc
+}
+
+// Validate reports every invalid entry at once.
+func (c *Config) Validate() error {
+	if len(c.Servers) == 0 {
+		return errors.New("mcpServers: no servers")
+	}
+	var errs []error
+	for _, name := range c.Names() {
+		if !serverName.MatchString(name) {
+			errs = append(errs, fmt.Errorf("server %q: name may only contain letters, digits, _ and -", name))
+		}
+		errs = append(errs, c.Servers[name].validate(name))
+	}
+	return errors.Join(errs...)
+}
+
+func (sc ServerConfig) validate(name string) error {
+	var errs []error
+	switch {
+	case sc.URL == "" && sc.Command == "":
+		errs = append(errs, fmt.Errorf("%s: needs a url or a command", name))
+	case sc.URL != "" && sc.Command != "":
+		errs = append(errs, fmt.Errorf("%s: has both a url and a command", name))
+	case sc.URL != "":
+		errs = append(errs, config.CheckURL(name+".url", sc.URL))
+	}
+	switch t := sc.TransportName(); t {
+	case TransportStreamable, TransportSSE:
+		if sc.Command != "" {
+			errs = append(errs, fmt.Errorf("%s.transport %q: a command needs the stdio transport", name, t))
+		}
+	case TransportStdio:
+		if sc.URL != "" {
+			errs = append(errs, fmt.Errorf("%s.transport %q: the stdio transport needs a command", name, t))
+		}
+		if sc.OAuth != nil || len(sc.Headers) > 0 {
+			errs = append(errs, fmt.Errorf("%s: headers and oauth only apply to HTTP transports", name))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s.transport %q: must be streamable, sse or stdio", name, t))
+	}
+	if sc.OAuth != nil && sc.OAuth.RedirectURI != "" {
+		errs = append(errs, config.CheckURL(name+".oauth.redirectUri", sc.OAuth.RedirectURI))
+	}
+	return errors.Join(errs...)
+}
+
+// TransportName returns the transport to use, guessing it when Transport is
+// empty.
+func (sc ServerConfig) TransportName() string {
+	switch {
+	case sc.Transport != "":
+		return sc.Transport
+	case sc.Command != "":
+		return TransportStdio
+	}
+	if u, err := url.Parse(sc.URL); err == nil && strings.HasSuffix(u.Path, "/sse") {
+		return TransportSSE
+	}
+	return TransportStreamable
+}
+
+// Names returns the names of the configured servers in order.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Servers))
+	for name := range c.Servers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// toolEnabled reports whether the tool may be offered.
+func (sc ServerConfig) toolEnabled(tool string) bool {
+	return sc.EnabledTools == nil || slices.Contains(sc.EnabledTools, tool)
+}
@@ -0,0 +1,530 @@
+// Package manager keeps a client connected to every server of an
+// mcpServers config file and offers their tools as one namespace, each
+// tool's name prefixed with its server's: the add tool of the server named
+// math is math.add.
+//
+// Each client reconnects on its own (see package reconnect), a server that
+// cannot be reached at start is tried again in the background, and Health
+// reports how every server is doing, so one server being down never keeps
+// the others from being used.
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/duaraghav8/gomcptest/reconnect"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/util"
+)
+
+// Separator joins a server name and a tool name.
+const Separator = "."
+
+// Status is how a server is doing.
+type Status string
+
+const (
+	StatusDisabled     Status = "disabled"     // disabled in the config
+	StatusConnecting   Status = "connecting"   // Start has not finished with it
+	StatusConnected    Status = "connected"    // ready
+	StatusReconnecting Status = "reconnecting" // lost, being reconnected
+	StatusDisconnected Status = "disconnected" // lost, reconnecting gave up for now
+	StatusFailed       Status = "failed"       // could not connect yet, retried in the background
+	StatusUnauthorized Status = "unauthorized" // needs OAuth authorization
+)
+
+// Options tune the manager. The zero value is usable.
+type Options struct {
+	// ClientInfo is sent to every server when initializing.
+	ClientInfo mcp.Implementation
+	// Logger receives the transports' messages; nil leaves mcp-go's
+	// default.
+	Logger util.Logger
+	// Stderr receives the stderr of stdio servers; nil discards it.
+	Stderr io.Writer
+	// TokenStore returns where to keep a server's OAuth tokens. When nil,
+	// or when it returns nil, they are kept in memory.
+	TokenStore func(server string) transport.TokenStore
+	// Authorize, if set, is called when a server asks for OAuth
+	// authorization while connecting, to run the authorization flow with
+	// handler. Without it such servers are reported as unauthorized.
+	Authorize func(ctx context.Context, server string, handler *transport.OAuthHandler) error
+	// OnNotification, if set, receives every server's notifications.
+	OnNotification func(server string, n mcp.JSONRPCNotification)
+	// Reconnect tunes reconnection, and the retries of servers that failed
+	// at start. Its OnStateChange is not used; Health reports the state
+	// instead.
+	Reconnect reconnect.Options
+}
+
+// Health is the state of one server.
+type Health struct {
+	Name      string `json:"name"`
+	Transport string `json:"transport"`
+	Status    Status `json:"status"`
+	// Since is when Status last changed.
+	Since time.Time `json:"since"`
+	// Error is why the server is not connected, or why it failed to answer
+	// a ping although connected.
+	Error  string              `json:"error,omitempty"`
+	Server *mcp.Implementation `json:"server,omitempty"`
+	// Tools counts the enabled tools.
+	Tools int `json:"tools"`
+	// Latency is how long a ping took.
+	Latency time.Duration `json:"latency,omitempty"`
+}
+
+// Manager holds one client per configured server.
+type Manager struct {
+	opts    Options
+	servers []*server
+
+	ctx    context.Context // every connection lives within it
+	cancel context.CancelFunc
+}
+
+type server struct {
+	name   string
+	cfg    ServerConfig
+	tokens transport.TokenStore
+
+	mu     sync.Mutex
+	c      *client.Client
+	info   *mcp.InitializeResult
+	tools  []mcp.Tool // enabled ones, as the server names them
+	status Status
+	err    error
+	since  time.Time
+	// clientID and clientSecret are the OAuth client from dynamic
+	// registration, reused by later connections.
+	clientID, clientSecret string
+}
+
+// New returns a manager for the servers in cfg. Nothing is connected until
+// Start.
+func New(cfg *Config, opts Options) *Manager {
+	if opts.ClientInfo.Name == "" {
+		opts.ClientInfo = mcp.Implementation{Name: "gomcptest-manager", Version: "0.1.0"}
+	}
+	m := &Manager{opts: opts}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	for _, name := range cfg.Names() {
+		s := &server{name: name, cfg: cfg.Servers[name], status: StatusConnecting, since: time.Now()}
+		if s.cfg.Disabled {
+			s.status = StatusDisabled
+		}
+		if s.cfg.OAuth != nil {
+			if opts.TokenStore != nil {
+				s.tokens = opts.TokenStore(name)
+			}
+			if s.tokens == nil {
+				s.tokens = client.NewMemoryTokenStore()
+			}
+		}
+		m.servers = append(m.servers, s)
+	}
+	return m
+}
+
+// Start connects to every enabled server at once and returns when each has
+// connected or failed; ctx bounds the handshakes. Failures are left for
+// Health to report, and servers that could not be reached are tried again
+// in the background until Close.
+func (m *Manager) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, s := range m.servers {
+		if s.cfg.Disabled {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if m.connect(ctx, s) {
+				go m.retry(s)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// retry connects to s again, waiting as the reconnect transport does between
+// attempts, until it succeeds, fails in a way waiting cannot fix,
+// Options.Reconnect.MaxAttempts runs out or the manager is closed.
+func (m *Manager) retry(s *server) {
+	ropts := m.opts.Reconnect.Defaults()
+	for attempt := 1; ropts.MaxAttempts == 0 || attempt <= ropts.MaxAttempts; attempt++ {
+		timer := time.NewTimer(ropts.Delay(attempt))
+		select {
+		case <-m.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		ctx, cancel := context.WithTimeout(m.ctx, ropts.Timeout)
+		again := m.connect(ctx, s)
+		cancel()
+		if !again {
+			return
+		}
+	}
+}
+
+// connect makes the client of s and initializes it. It reports whether s
+// failed in a way that may pass, such as the server being down, and so is
+// worth trying again; a server that needs authorization is not.
+func (m *Manager) connect(ctx context.Context, s *server) (retry bool) {
+	ropts := m.opts.Reconnect
+	ropts.OnStateChange = func(state reconnect.State, err error) { m.stateChanged(s, state, err) }
+	c := client.NewClient(reconnect.New(func(ctx context.Context) (transport.Interface, error) {
+		return m.dial(ctx, s)
+	}, ropts))
+	c.OnNotification(func(n mcp.JSONRPCNotification) {
+		if n.Method == mcp.MethodNotificationToolsListChanged {
+			go m.refreshTools(s)
+		}
+		if m.opts.OnNotification != nil {
+			m.opts.OnNotification(s.name, n)
+		}
+	})
+	if err := c.Start(m.ctx); err != nil {
+		s.set(StatusFailed, fmt.Errorf("connect: %w", err))
+		return true
+	}
+
+	req := mcp.InitializeRequest{}
+	req.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	req.Params.ClientInfo = m.opts.ClientInfo
+	info, err := c.Initialize(ctx, req)
+	if client.IsOAuthAuthorizationRequiredError(err) {
+		if m.opts.Authorize == nil {
+			c.Close()
+			s.set(StatusUnauthorized, errors.New("OAuth authorization required"))
+			return false
+		}
+		handler := client.GetOAuthHandler(err)
+		if err := m.opts.Authorize(ctx, s.name, handler); err != nil {
+			c.Close()
+			s.set(StatusUnauthorized, fmt.Errorf("authorize: %w", err))
+			return false
+		}
+		s.mu.Lock()
+		s.clientID, s.clientSecret = handler.GetClientID(), handler.GetClientSecret()
+		s.mu.Unlock()
+		info, err = c.Initialize(ctx, req)
+	}
+	if err != nil {
+		c.Close()
+		s.set(StatusFailed, fmt.Errorf("initialize: %w", err))
+		return true
+	}
+
+	s.mu.Lock()
+	if m.ctx.Err() != nil {
+		// Closed meanwhile.
+		s.mu.Unlock()
+		c.Close()
+		return false
+	}
+	s.c, s.info = c, info
+	s.mu.Unlock()
+	if err := m.loadTools(ctx, s); err != nil {
+		s.set(StatusConnected, err)
+		return false
+	}
+	s.set(StatusConnected, nil)
+	return false
+}
+
+// dial opens and starts a new connection to s.
+func (m *Manager) dial(ctx context.Context, s *server) (transport.Interface, error) {
+	cfg := s.cfg
+	switch cfg.TransportName() {
+	case TransportStdio:
+		env := make([]string, 0, len(cfg.Env))
+		for _, k := range slices.Sorted(maps.Keys(cfg.Env)) {
+			env = append(env, k+"="+cfg.Env[k])
+		}
+		var opts []transport.StdioOption
+		if m.opts.Logger != nil {
+			opts = append(opts, transport.WithCommandLogger(m.opts.Logger))
+		}
+		stdio := transport.NewStdioWithOptions(cfg.Command, env, cfg.Args, opts...)
+		if err := stdio.Start(ctx); err != nil {
+			return nil, err
+		}
+		// The server blocks once the pipe fills, so drain it either way.
+		dst := m.opts.Stderr
+		if dst == nil {
+			dst = io.Discard
+		}
+		go io.Copy(dst, stdio.Stderr())
+		return stdio, nil
+
+	case TransportSSE:
+		opts := []transport.ClientOption{transport.WithHeaders(cfg.Headers)}
+		if m.opts.Logger != nil {
+			opts = append(opts, transport.WithSSELogger(m.opts.Logger))
+		}
+		if oauth, ok := s.oauthConfig(); ok {
+			opts = append(opts, transport.WithOAuth(oauth))
+		}
+		sse, err := transport.NewSSE(cfg.URL, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if err := sse.Start(ctx); err != nil {
+			return nil, err
+		}
+		return sse, nil
+	}
+
+	opts := []transport.StreamableHTTPCOption{
+		transport.WithContinuousListening(),
+		transport.WithHTTPHeaders(cfg.Headers),
+	}
+	if m.opts.Logger != nil {
+		opts = append(opts, transport.WithHTTPLogger(m.opts.Logger))
+	}
+	if oauth, ok := s.oauthConfig(); ok {
+		opts = append(opts, transport.WithHTTPOAuth(oauth))
+	}
+	trans, err := transport.NewStreamableHTTP(cfg.URL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := trans.Start(ctx); err != nil {
+		return nil, err
+	}
+	return trans, nil
+}
+
+// oauthConfig returns the OAuth settings for a new connection to s.
+func (s *server) oauthConfig() (transport.OAuthConfig, bool) {
+	o := s.cfg.OAuth
+	if o == nil {
+		return transport.OAuthConfig{}, false
+	}
+	s.mu.Lock()
+	clientID, clientSecret := s.clientID, s.clientSecret
+	s.mu.Unlock()
+	if o.ClientID != "" {
+		clientID, clientSecret = o.ClientID, o.ClientSecret
+	}
+	return transport.OAuthConfig{
+		ClientID:              clientID,
+		ClientSecret:          clientSecret,
+		RedirectURI:           o.RedirectURI,
+		Scopes:                o.Scopes,
+		TokenStore:            s.tokens,
+		AuthServerMetadataURL: o.AuthServerMetadataURL,
+		PKCEEnabled:           clientSecret == "",
+	}, true
+}
+
+func (m *Manager) stateChanged(s *server, state reconnect.State, err error) {
+	switch state {
+	case reconnect.Reconnecting:
+		s.set(StatusReconnecting, err)
+	case reconnect.Disconnected:
+		s.set(StatusDisconnected, err)
+	case reconnect.Connected:
+		s.set(StatusConnected, err)
+		// A restarted server may offer different tools.
+		go m.refreshTools(s)
+	}
+}
+
+func (m *Manager) refreshTools(s *server) {
+	ctx, cancel := context.WithTimeout(m.ctx, 30*time.Second)
+	defer cancel()
+	if err := m.loadTools(ctx, s); err != nil {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+	}
+}
+
+// loadTools caches the enabled tools of s.
+func (m *Manager) loadTools(ctx context.Context, s *server) error {
+	s.mu.Lock()
+	c, info := s.c, s.info
+	s.mu.Unlock()
+	if c == nil || info.Capabilities.Tools == nil {
+		return nil
+	}
+	res, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return fmt.Errorf("list tools: %w", err)
+	}
+	var tools []mcp.Tool
+	for _, t := range res.Tools {
+		if s.cfg.toolEnabled(t.Name) {
+			tools = append(tools, t)
+		}
+	}
+	s.mu.Lock()
+	s.tools = tools
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *server) set(status Status, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status != status {
+		s.since = time.Now()
+	}
+	s.status, s.err = status, err
+}
+
+// Tools returns the enabled tools of every connected server, named with
+// their server's prefix, by server and then as each server lists them.
+func (m *Manager) Tools() []mcp.Tool {
+	var out []mcp.Tool
+	for _, s := range m.servers {
+		s.mu.Lock()
+		for _, t := range s.tools {
+			t.Name = s.name + Separator + t.Name
+			out = append(out, t)
+		}
+		s.mu.Unlock()
+	}
+	return out
+}
+
+// Tool looks up a tool by its prefixed name.
+func (m *Manager) Tool(name string) (mcp.Tool, bool) {
+	for _, t := range m.Tools() {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return mcp.Tool{}, false
+}
+
+// CallTool calls a tool by its prefixed name on the server it belongs to.
+func (m *Manager) CallTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	c, s, tool, err := m.resolve(req.Params.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !s.cfg.toolEnabled(tool) {
+		return nil, fmt.Errorf("tool %s is not enabled for server %s", tool, s.name)
+	}
+	req.Params.Name = tool
+	return c.CallTool(ctx, req)
+}
+
+// resolve splits a prefixed name and finds the server's client.
+func (m *Manager) resolve(name string) (*client.Client, *server, string, error) {
+	prefix, tool, ok := strings.Cut(name, Separator)
+	if !ok || tool == "" {
+		return nil, nil, "", fmt.Errorf("tool %q: want server%stool", name, Separator)
+	}
+	s := m.server(prefix)
+	if s == nil {
+		return nil, nil, "", fmt.Errorf("tool %q: unknown server %q", name, prefix)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.c == nil {
+		if s.err != nil {
+			return nil, nil, "", fmt.Errorf("server %s is not available (%s): %w", s.name, s.status, s.err)
+		}
+		return nil, nil, "", fmt.Errorf("server %s is not available (%s)", s.name, s.status)
+	}
+	return s.c, s, tool, nil
+}
+
+func (m *Manager) server(name string) *server {
+	for _, s := range m.servers {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Client returns the client of a server, if it has connected.
+func (m *Manager) Client(name string) (*client.Client, bool) {
+	s := m.server(name)
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c, s.c != nil
+}
+
+// Health reports on every server, in name order. Connected servers are
+// pinged, all at once, to measure their latency; ctx bounds the pings.
+func (m *Manager) Health(ctx context.Context) []Health {
+	out := make([]Health, len(m.servers))
+	var wg sync.WaitGroup
+	for i, s := range m.servers {
+		s.mu.Lock()
+		h := Health{
+			Name:      s.name,
+			Transport: s.cfg.TransportName(),
+			Status:    s.status,
+			Since:     s.since,
+			Tools:     len(s.tools),
+		}
+		if s.err != nil {
+			h.Error = s.err.Error()
+		}
+		if s.info != nil {
+			h.Server = &s.info.ServerInfo
+		}
+		c, connected := s.c, s.status == StatusConnected
+		s.mu.Unlock()
+
+		out[i] = h
+		if !connected {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			if err := c.Ping(ctx); err != nil {
+				out[i].Error = fmt.Sprintf("ping: %v", err)
+				return
+			}
+			out[i].Latency = time.Since(start)
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// Close disconnects from every server and stops retrying the ones that
+// failed.
+func (m *Manager) Close() error {
+	// Cancel first, so that a connection still being made is dropped.
+	m.cancel()
+	var errs []error
+	for _, s := range m.servers {
+		s.mu.Lock()
+		c := s.c
+		s.c = nil
+		s.mu.Unlock()
+		if c == nil {
+			continue
+		}
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,638 @@
+// Package reconnect keeps an MCP client working across lost connections and
+// server restarts. Its Transport wraps the client's real transport: when a
+// request fails because the connection is gone, or because the server no
+// longer knows the session (HTTP 404, as after a restart), it dials a new
+// connection with exponential backoff and jitter, replays the initialize
+// handshake the client made with its original parameters, and subscribes
+// again to the resources the client was subscribed to. The client does not
+// notice:
+//
+//	t := reconnect.New(dial, reconnect.Options{OnStateChange: report})
+//	c := client.NewClient(t)
+//
+// A request whose failure shows that it never reached the server is sent
+// again on the new connection. Any other request in flight when the
+// connection dropped fails, since the server may have acted on it.
+package reconnect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ErrClosed is returned for requests made after Close.
+var ErrClosed = errors.New("reconnect: transport closed")
+
+// errNotConnected is the cause of an outage started because the previous
+// one gave up.
+var errNotConnected = errors.New("not connected")
+
+// State is the state of the connection, as reported to
+// Options.OnStateChange.
+type State int
+
+const (
+	// Connected means a connection is up and initialized, either the first
+	// one or a new one after an outage.
+	Connected State = iota + 1
+	// Reconnecting means the connection was lost and new ones are being
+	// tried.
+	Reconnecting
+	// Disconnected means reconnecting gave up after Options.MaxAttempts.
+	// The next request that fails tries again.
+	Disconnected
+	// Closed means Close was called.
+	Closed
+)
+
+func (s State) String() string {
+	switch s {
+	case Connected:
+		return "connected"
+	case Reconnecting:
+		return "reconnecting"
+	case Disconnected:
+		return "disconnected"
+	case Closed:
+		return "closed"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Dialer opens a new connection to the server and starts it. ctx is the
+// one passed to Transport.Start and lasts as long as the Transport.
+type Dialer func(ctx context.Context) (transport.Interface, error)
+
+// Options tune reconnection. The zero value is usable.
+type Options struct {
+	// MinDelay and MaxDelay bound the wait between attempts, which doubles
+	// from MinDelay up to MaxDelay and is shortened by a random amount of up
+	// to half, so that clients of a restarted server do not all come back
+	// at once. The first attempt is made straight away. They default to
+	// 500ms and 30s.
+	MinDelay, MaxDelay time.Duration
+	// MaxAttempts is how many attempts are made per outage before giving
+	// up. Zero means no limit.
+	MaxAttempts int
+	// Timeout bounds each attempt's handshake and each keepalive ping. It
+	// defaults to 30s.
+	Timeout time.Duration
+	// PingInterval, if set, is how often the server is pinged so that a
+	// lost connection is noticed between requests, which matters when the
+	// client is only waiting for notifications.
+	PingInterval time.Duration
+	// OnStateChange, if set, is told about every change of state. err is
+	// the cause for Reconnecting, the last attempt's error for
+	// Disconnected, and for Connected any resource that could not be
+	// subscribed to again.
+	OnStateChange func(state State, err error)
+}
+
+// Transport is a transport.Interface that reconnects on its own.
+type Transport struct {
+	dial Dialer
+	opts Options
+
+	ctx    context.Context // from Start; every connection lives within it
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	conn   transport.Interface
+	gen    int     // counts connections, so that one loss is acted on once
+	outage *outage // non-nil while reconnecting
+	closed bool
+
+	// What a new connection is set up with.
+	initialize    *transport.JSONRPCRequest
+	initialized   bool
+	subscriptions map[string]bool
+	notify        func(mcp.JSONRPCNotification)
+	handler       transport.RequestHandler
+
+	ids atomic.Int64 // for the transport's own requests
+}
+
+// outage is one round of reconnection attempts.
+type outage struct {
+	done chan struct{}
+	err  error // set before done is closed; nil if a connection was made
+}
+
+// Defaults returns o with the defaults filled in for unset fields.
+func (o Options) Defaults() Options {
+	if o.MinDelay <= 0 {
+		o.MinDelay = 500 * time.Millisecond
+	}
+	if o.MaxDelay < o.MinDelay {
+		o.MaxDelay = max(30*time.Second, o.MinDelay)
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 30 * time.Second
+	}
+	return o
+}
+
+// Delay returns the wait before retry n, counting from 1, as described for
+// MinDelay and MaxDelay.
+func (o Options) Delay(n int) time.Duration {
+	o = o.Defaults()
+	d := o.MaxDelay
+	if n < 32 {
+		d = min(o.MinDelay<<(n-1), o.MaxDelay)
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// New returns a Transport that opens its connections with dial.
+func New(dial Dialer, opts Options) *Transport {
+	return &Transport{dial: dial, opts: opts.Defaults(), subscriptions: make(map[string]bool)}
+}
+
+// Start opens the first connection. A failure here is returned rather than
+// retried, so that a wrong address is reported at once.
+func (t *Transport) Start(ctx context.Context) error {
+	conn, err := t.dial(ctx)
+	if err != nil {
+		return err
+	}
+	t.ctx, t.cancel = context.WithCancel(ctx)
+	t.mu.Lock()
+	t.conn = conn
+	t.mu.Unlock()
+	t.attach(conn)
+	if t.opts.PingInterval > 0 {
+		go t.keepalive()
+	}
+	return nil
+}
+
+// attach routes what conn receives to the handlers set on t.
+func (t *Transport) attach(conn transport.Interface) {
+	conn.SetNotificationHandler(func(n mcp.JSONRPCNotification) {
+		t.mu.Lock()
+		fn := t.notify
+		t.mu.Unlock()
+		if fn != nil {
+			fn(n)
+		}
+	})
+	if bidi, ok := conn.(transport.BidirectionalInterface); ok {
+		bidi.SetRequestHandler(func(ctx context.Context, req transport.JSONRPCRequest) (*transport.JSONRPCResponse, error) {
+			t.mu.Lock()
+			fn := t.handler
+			t.mu.Unlock()
+			if fn == nil {
+				return nil, fmt.Errorf("no handler for %s", req.Method)
+			}
+			return fn(ctx, req)
+		})
+	}
+	if setter, ok := conn.(interface{ SetConnectionLostHandler(func(error)) }); ok {
+		setter.SetConnectionLostHandler(func(err error) {
+			// Only the loss of the connection in use counts. A redial
+			// attempt that failed was closed and given up on, and a late
+			// report from it must not take down the one that replaced it;
+			// a connection lost before it was put in use is noticed by
+			// the next request or ping instead.
+			t.mu.Lock()
+			gen, current := t.gen, t.conn == conn
+			t.mu.Unlock()
+			if current && t.replayable() {
+				go t.reconnect(t.ctx, gen, err)
+			}
+		})
+	}
+}
+
+// SendRequest sends req on the current connection, reconnecting if it turns
+// out to be lost.
+func (t *Transport) SendRequest(ctx context.Context, req transport.JSONRPCRequest) (*transport.JSONRPCResponse, error) {
+	if req.Method == string(mcp.MethodInitialize) {
+		t.mu.Lock()
+		t.initialize = &req
+		t.mu.Unlock()
+	}
+	var resp *transport.JSONRPCResponse
+	err := t.do(ctx, func(conn transport.Interface) error {
+		var err error
+		resp, err = conn.SendRequest(ctx, req)
+		return err
+	})
+	if err == nil && resp.Error == nil {
+		t.track(req)
+	}
+	return resp, err
+}
+
+// SendNotification sends n on the current connection, reconnecting if it
+// turns out to be lost.
+func (t *Transport) SendNotification(ctx context.Context, n mcp.JSONRPCNotification) error {
+	err := t.do(ctx, func(conn transport.Interface) error {
+		return conn.SendNotification(ctx, n)
+	})
+	if err == nil && n.Method == "notifications/initialized" {
+		t.mu.Lock()
+		t.initialized = true
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// do runs send on the current connection. If the connection is lost, and
+// the handshake has been made so that it can be replayed, it reconnects and
+// runs send again if the first try cannot have reached the server.
+func (t *Transport) do(ctx context.Context, send func(transport.Interface) error) error {
+	conn, gen, err := t.current(ctx)
+	if err != nil {
+		return err
+	}
+	if conn == nil {
+		// The last outage ended without a connection.
+		if err := t.reconnect(ctx, gen, errNotConnected); err != nil {
+			return err
+		}
+		if conn, _, err = t.current(ctx); err != nil {
+			return err
+		}
+		return send(conn)
+	}
+	err = send(conn)
+	if err == nil || !lost(err) || !t.replayable() {
+		return err
+	}
+	if rerr := t.reconnect(ctx, gen, err); rerr != nil || !undelivered(err) {
+		return err
+	}
+	conn, _, cerr := t.current(ctx)
+	if cerr != nil {
+		return err
+	}
+	return send(conn)
+}
+
+// current returns the connection to use, waiting for an outage to end.
+func (t *Transport) current(ctx context.Context) (transport.Interface, int, error) {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil, 0, ErrClosed
+	}
+	o := t.outage
+	if o == nil {
+		defer t.mu.Unlock()
+		return t.conn, t.gen, nil
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-o.done:
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	}
+	if o.err != nil {
+		return nil, 0, o.err
+	}
+	return t.current(ctx)
+}
+
+func (t *Transport) replayable() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.initialize != nil && t.initialized
+}
+
+// track keeps the set of subscribed resources up to date.
+func (t *Transport) track(req transport.JSONRPCRequest) {
+	if req.Method != "resources/subscribe" && req.Method != "resources/unsubscribe" {
+		return
+	}
+	raw, err := json.Marshal(req.Params)
+	if err != nil {
+		return
+	}
+	var params mcp.SubscribeParams
+	if err := json.Unmarshal(raw, &params); err != nil || params.URI == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if req.Method == "resources/subscribe" {
+		t.subscriptions[params.URI] = true
+	} else {
+		delete(t.subscriptions, params.URI)
+	}
+}
+
+// reconnect replaces connection gen, which failed with cause, and waits
+// until that is done or ctx ends. Several callers noticing the same loss
+// share one round of attempts.
+func (t *Transport) reconnect(ctx context.Context, gen int, cause error) error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return ErrClosed
+	}
+	if t.gen != gen {
+		// Already replaced.
+		t.mu.Unlock()
+		return nil
+	}
+	o := t.outage
+	if o == nil {
+		o = &outage{done: make(chan struct{})}
+		t.outage = o
+		go t.recover(t.conn, gen+1, cause, o)
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-o.done:
+		return o.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// recover makes connection gen to replace old, trying until it succeeds,
+// gives up or the transport is closed.
+func (t *Transport) recover(old transport.Interface, gen int, cause error, o *outage) {
+	t.changed(Reconnecting, cause)
+	if old != nil {
+		// Stop the dead connection's listeners; how it ends is of no
+		// interest.
+		old.Close()
+	}
+
+	var (
+		conn   transport.Interface
+		subErr error
+		err    error
+	)
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && !t.sleep(t.opts.Delay(attempt-1)) {
+			err = ErrClosed
+			break
+		}
+		conn, subErr, err = t.redial()
+		if err == nil || t.ctx.Err() != nil || (t.opts.MaxAttempts > 0 && attempt >= t.opts.MaxAttempts) {
+			break
+		}
+	}
+
+	t.mu.Lock()
+	if err == nil && t.closed {
+		conn.Close()
+		err = ErrClosed
+	}
+	if err == nil {
+		t.conn, t.gen = conn, gen
+	} else {
+		// The old connection is closed; the next request starts over.
+		t.conn = nil
+	}
+	t.outage = nil
+	o.err = err
+	close(o.done)
+	t.mu.Unlock()
+
+	switch {
+	case err == nil:
+		t.changed(Connected, subErr)
+	case !errors.Is(err, ErrClosed):
+		t.changed(Disconnected, err)
+	}
+}
+
+// sleep waits for d and reports whether the transport is still open.
+func (t *Transport) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-t.ctx.Done():
+		return false
+	}
+}
+
+// redial opens a connection and replays the handshake and subscriptions on
+// it. Resources that cannot be subscribed to again, perhaps because they
+// are gone, are dropped and reported in subErr without failing the attempt.
+func (t *Transport) redial() (conn transport.Interface, subErr error, err error) {
+	conn, err = t.dial(t.ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	t.attach(conn)
+
+	ctx, cancel := context.WithTimeout(t.ctx, t.opts.Timeout)
+	defer cancel()
+	t.mu.Lock()
+	init := *t.initialize
+	uris := slices.Sorted(maps.Keys(t.subscriptions))
+	t.mu.Unlock()
+
+	resp, err := conn.SendRequest(ctx, init)
+	if err == nil && resp.Error != nil {
+		err = resp.Error.AsError()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("initialize: %w", err)
+	}
+	var res mcp.InitializeResult
+	if err := json.Unmarshal(resp.Result, &res); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("initialize: %w", err)
+	}
+	if hc, ok := conn.(transport.HTTPConnection); ok {
+		hc.SetProtocolVersion(res.ProtocolVersion)
+	}
+	initialized := mcp.JSONRPCNotification{
+		JSONRPC:      mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{Method: "notifications/initialized"},
+	}
+	if err := conn.SendNotification(ctx, initialized); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("initialized: %w", err)
+	}
+
+	var errs []error
+	for _, uri := range uris {
+		req := t.request("resources/subscribe", mcp.SubscribeParams{URI: uri})
+		resp, err := conn.SendRequest(ctx, req)
+		if err == nil && resp.Error != nil {
+			err = resp.Error.AsError()
+		}
+		if err != nil {
+			if lost(err) {
+				conn.Close()
+				return nil, nil, fmt.Errorf("subscribe %s: %w", uri, err)
+			}
+			t.mu.Lock()
+			delete(t.subscriptions, uri)
+			t.mu.Unlock()
+			errs = append(errs, fmt.Errorf("subscribe %s: %w", uri, err))
+		}
+	}
+	return conn, errors.Join(errs...), nil
+}
+
+// request builds a request of the transport's own, with an ID that cannot
+// clash with the client's numeric ones.
+func (t *Transport) request(method string, params any) transport.JSONRPCRequest {
+	return transport.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      mcp.NewRequestId(fmt.Sprintf("reconnect-%d", t.ids.Add(1))),
+		Method:  method,
+		Params:  params,
+	}
+}
+
+// keepalive pings the server every Options.PingInterval and reconnects
+// when a ping shows the connection is lost or goes unanswered.
+func (t *Transport) keepalive() {
+	ticker := time.NewTicker(t.opts.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.ctx.Done():
+			return
+		}
+		t.mu.Lock()
+		conn, gen, busy := t.conn, t.gen, t.outage != nil
+		t.mu.Unlock()
+		if busy || !t.replayable() {
+			continue
+		}
+		if conn == nil {
+			t.reconnect(t.ctx, gen, errNotConnected)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(t.ctx, t.opts.Timeout)
+		_, err := conn.SendRequest(ctx, t.request(string(mcp.MethodPing), nil))
+		timedOut := ctx.Err() != nil && t.ctx.Err() == nil
+		cancel()
+		if err != nil && (lost(err) || timedOut) {
+			t.reconnect(t.ctx, gen, err)
+		}
+	}
+}
+
+func (t *Transport) changed(state State, err error) {
+	if t.opts.OnStateChange != nil {
+		t.opts.OnStateChange(state, err)
+	}
+}
+
+// Close closes the current connection and stops reconnecting.
+func (t *Transport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	conn := t.conn
+	t.mu.Unlock()
+
+	if t.cancel != nil {
+		t.cancel()
+	}
+	var err error
+	if conn != nil {
+		err = conn.Close()
+	}
+	t.changed(Closed, nil)
+	return err
+}
+
+// SetNotificationHandler sets the handler for notifications on this and
+// every later connection.
+func (t *Transport) SetNotificationHandler(handler func(mcp.JSONRPCNotification)) {
+	t.mu.Lock()
+	t.notify = handler
+	t.mu.Unlock()
+}
+
+// SetRequestHandler sets the handler for requests from the server on this
+// and every later connection.
+func (t *Transport) SetRequestHandler(handler transport.RequestHandler) {
+	t.mu.Lock()
+	t.handler = handler
+	t.mu.Unlock()
+}
+
+// SetProtocolVersion passes the negotiated version to HTTP connections.
+func (t *Transport) SetProtocolVersion(version string) {
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+	if hc, ok := conn.(transport.HTTPConnection); ok && conn != nil {
+		hc.SetProtocolVersion(version)
+	}
+}
+
+// GetSessionId returns the session ID of the current connection.
+func (t *Transport) GetSessionId() string {
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+	if conn == nil {
+		return ""
+	}
+	return conn.GetSessionId()
+}
+
+// lost reports whether err means the connection or the session is gone, as
+// opposed to the server answering with an error.
+func lost(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if undelivered(err) {
+		return true
+	}
+	for _, target := range []error{io.EOF, io.ErrUnexpectedEOF, syscall.ECONNRESET} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// undelivered reports whether err shows that a request never reached a
+// server that knew the session, so sending it again cannot repeat it.
+func undelivered(err error) bool {
+	for _, target := range []error{transport.ErrSessionTerminated, syscall.ECONNREFUSED, syscall.EPIPE, os.ErrClosed, io.ErrClosedPipe} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	// The SSE transport reports the server's answer to an unknown session
+	// only as text.
+	msg := err.Error()
+	return strings.Contains(msg, "Invalid session ID") || strings.Contains(msg, "status 404")
+}
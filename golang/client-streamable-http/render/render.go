@@ -0,0 +1,384 @@
+// Package render prints MCP content for people: text as it is, images,
+// audio and blobs saved to files named after their MIME type, resource
+// links, embedded resources and structured content as indented JSON or a
+// table.
+//
+// Problems along the way, such as a file that cannot be written or a link
+// that cannot be read, are shown in place of the content rather than
+// returned, so one bad item does not hide the rest of a result.
+package render
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Options control how content is rendered.
+type Options struct {
+	// Dir is where binary content is saved. Empty means it is only
+	// described.
+	Dir string
+	// Table shows structured content as a table where its shape allows:
+	// an object as name/value rows, a list of objects as one row each.
+	Table bool
+	// Follow, if set, reads the resources that links point to so their
+	// contents are shown too.
+	Follow func(ctx context.Context, uri string) (*mcp.ReadResourceResult, error)
+}
+
+// Renderer writes content to w.
+type Renderer struct {
+	w    io.Writer
+	opts Options
+}
+
+// New returns a renderer writing to w.
+func New(w io.Writer, opts Options) *Renderer {
+	return &Renderer{w: w, opts: opts}
+}
+
+// ToolResult renders every content item of a tool result followed by its
+// structured content.
+func (r *Renderer) ToolResult(ctx context.Context, res *mcp.CallToolResult) {
+	if res.IsError {
+		fmt.Fprintln(r.w, "[tool error]")
+	}
+	for _, c := range res.Content {
+		r.Content(ctx, c)
+	}
+	if res.StructuredContent != nil {
+		if len(res.Content) > 0 {
+			fmt.Fprintln(r.w, "[structured content]")
+		}
+		r.Structured(res.StructuredContent)
+	}
+}
+
+// Content renders one content item.
+func (r *Renderer) Content(ctx context.Context, content mcp.Content) {
+	switch c := content.(type) {
+	case mcp.TextContent:
+		fmt.Fprintln(r.w, c.Text)
+	case mcp.ImageContent:
+		r.binary("image", c.MIMEType, "", c.Data)
+	case mcp.AudioContent:
+		r.binary("audio", c.MIMEType, "", c.Data)
+	case mcp.ResourceLink:
+		r.link(ctx, c)
+	case mcp.EmbeddedResource:
+		r.Resource(c.Resource)
+	default:
+		b, err := json.Marshal(content)
+		if err != nil {
+			fmt.Fprintf(r.w, "[unknown content %T]\n", content)
+			return
+		}
+		fmt.Fprintf(r.w, "[unknown content] %s\n", b)
+	}
+}
+
+// Resource renders the contents of a resource within other content: text
+// under a header naming it, blobs saved like images.
+func (r *Renderer) Resource(contents mcp.ResourceContents) {
+	if c, ok := contents.(mcp.TextResourceContents); ok {
+		fmt.Fprintf(r.w, "[resource %s]\n", describe(c.URI, c.MIMEType))
+	}
+	r.ResourceBody(contents)
+}
+
+// ResourceBody renders the contents of a resource on their own, as read:
+// text as it is, blobs saved like images.
+func (r *Renderer) ResourceBody(contents mcp.ResourceContents) {
+	switch c := contents.(type) {
+	case mcp.TextResourceContents:
+		fmt.Fprintln(r.w, c.Text)
+	case mcp.BlobResourceContents:
+		r.binary("resource", c.MIMEType, c.URI, c.Blob)
+	}
+}
+
+func (r *Renderer) link(ctx context.Context, l mcp.ResourceLink) {
+	fmt.Fprintf(r.w, "[link %s] %s", describe(l.URI, l.MIMEType), l.Name)
+	if l.Description != "" {
+		fmt.Fprintf(r.w, ": %s", l.Description)
+	}
+	fmt.Fprintln(r.w)
+	if r.opts.Follow == nil {
+		return
+	}
+	res, err := r.opts.Follow(ctx, l.URI)
+	if err != nil {
+		fmt.Fprintf(r.w, "[cannot read %s: %v]\n", l.URI, err)
+		return
+	}
+	for _, c := range res.Contents {
+		r.Resource(c)
+	}
+}
+
+// describe names a resource by URI and, if known, MIME type.
+func describe(uri, mimeType string) string {
+	if mimeType == "" {
+		return uri
+	}
+	return uri + " " + mimeType
+}
+
+// binary decodes base64 data and saves it, or only describes it when there
+// is no directory to save to.
+func (r *Renderer) binary(kind, mimeType, uri, data string) {
+	label := kind
+	if uri != "" {
+		label += " " + uri
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		fmt.Fprintf(r.w, "[%s %s: invalid base64: %v]\n", label, mimeType, err)
+		return
+	}
+	if r.opts.Dir == "" {
+		fmt.Fprintf(r.w, "[%s %s, %s]\n", label, mimeType, size(len(raw)))
+		return
+	}
+	file, err := save(r.opts.Dir, fileStem(kind, uri), Extension(mimeType), raw)
+	if err != nil {
+		fmt.Fprintf(r.w, "[%s %s, %s: not saved: %v]\n", label, mimeType, size(len(raw)), err)
+		return
+	}
+	fmt.Fprintf(r.w, "[%s %s, %s] saved to %s\n", label, mimeType, size(len(raw)), file)
+}
+
+// fileStem picks a file name without extension: the last element of the
+// resource URI when there is one, otherwise the kind of content.
+func fileStem(kind, uri string) string {
+	if uri != "" {
+		base := path.Base(uri)
+		base = strings.TrimSuffix(base, path.Ext(base))
+		if base != "" && base != "." && base != "/" && !strings.ContainsAny(base, `:\`) {
+			return base
+		}
+	}
+	return kind
+}
+
+// save writes data to dir/stem+ext, or to stem-2+ext and so on if that
+// file exists, and returns the path used.
+func save(dir, stem, ext string, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	for i := 1; ; i++ {
+		name := stem + ext
+		if i > 1 {
+			name = fmt.Sprintf("%s-%d%s", stem, i, ext)
+		}
+		file := filepath.Join(dir, name)
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return file, err
+	}
+}
+
+// extensions covers common media types that the mime package only knows
+// when the system has a mime.types file.
+var extensions = map[string]string{
+	"audio/mpeg":      ".mp3",
+	"audio/mp4":       ".m4a",
+	"audio/ogg":       ".ogg",
+	"audio/wav":       ".wav",
+	"audio/x-wav":     ".wav",
+	"audio/webm":      ".weba",
+	"audio/flac":      ".flac",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/svg+xml":   ".svg",
+	"image/bmp":       ".bmp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+	"text/markdown":   ".md",
+	"text/csv":        ".csv",
+}
+
+// Extension returns the file extension for a MIME type, ".bin" if unknown.
+func Extension(mimeType string) string {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return ".bin"
+	}
+	if ext, ok := extensions[mediaType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+func size(n int) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%d B", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
+
+// Structured renders structured content as indented JSON or, with
+// Options.Table, as a table when it is an object or a list of objects.
+func (r *Renderer) Structured(v any) {
+	if r.opts.Table && r.table(v) {
+		return
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(r.w, "%v\n", v)
+		return
+	}
+	fmt.Fprintln(r.w, string(b))
+}
+
+// table writes v as a table and reports whether its shape allowed one. An
+// object becomes name/value rows, except that its lists of objects follow
+// as tables of their own.
+func (r *Renderer) table(v any) bool {
+	switch v := v.(type) {
+	case map[string]any:
+		// A single property wrapping the real value, as in {"data": {...}},
+		// is unwrapped.
+		if len(v) == 1 {
+			for _, inner := range v {
+				if tableShaped(inner) {
+					return r.table(inner)
+				}
+			}
+		}
+		rows := [][]string{{"NAME", "VALUE"}}
+		var lists []string
+		for _, k := range sortedKeys(v) {
+			if list, ok := v[k].([]any); ok && len(list) > 0 && objectList(list) {
+				lists = append(lists, k)
+				continue
+			}
+			rows = append(rows, []string{k, cell(v[k])})
+		}
+		if len(rows) > 1 {
+			r.writeTable(rows)
+		}
+		for _, k := range lists {
+			fmt.Fprintf(r.w, "\n%s:\n", k)
+			r.writeTable(listRows(v[k].([]any)))
+		}
+		return true
+	case []any:
+		if len(v) == 0 || !objectList(v) {
+			return false
+		}
+		r.writeTable(listRows(v))
+		return true
+	}
+	return false
+}
+
+func tableShaped(v any) bool {
+	switch v := v.(type) {
+	case map[string]any:
+		return true
+	case []any:
+		return len(v) > 0 && objectList(v)
+	}
+	return false
+}
+
+// listRows lays out a list of objects with one column per key.
+func listRows(list []any) [][]string {
+	var columns []string
+	for _, item := range list {
+		for _, k := range sortedKeys(item.(map[string]any)) {
+			if !slices.Contains(columns, k) {
+				columns = append(columns, k)
+			}
+		}
+	}
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = strings.ToUpper(c)
+	}
+	rows := [][]string{header}
+	for _, item := range list {
+		obj := item.(map[string]any)
+		row := make([]string, len(columns))
+		for i, c := range columns {
+			if val, ok := obj[c]; ok {
+				row[i] = cell(val)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (r *Renderer) writeTable(rows [][]string) {
+	tw := tabwriter.NewWriter(r.w, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
+
+func objectList(list []any) bool {
+	for _, item := range list {
+		if _, ok := item.(map[string]any); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// cell formats a value for a table cell: strings as they are, anything
+// else as compact JSON, on one line.
+func cell(v any) string {
+	if s, ok := v.(string); ok {
+		return strings.ReplaceAll(s, "\n", " ")
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
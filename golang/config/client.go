@@ -0,0 +1,11 @@
+package config
+
+// Client holds where a client connects to.
+type Client struct {
+	URL string `flag:"url" env:"MCP_SERVER_URL" json:"url" usage:"URL of the MCP server"`
+}
+
+// Validate checks that URL is an absolute http or https URL.
+func (c *Client) Validate() error {
+	return CheckURL("url", c.URL)
+}
@@ -0,0 +1,199 @@
+// Package config loads the settings shared by the example MCP servers and
+// clients. Every setting can be given in a JSON config file, an environment
+// variable or a command-line flag; when a setting appears in more than one
+// place the flag wins over the environment, which wins over the file.
+//
+// A setting is a struct field tagged with its flag name, environment
+// variable, config file key and usage text:
+//
+//	Addr string `flag:"addr" env:"MCP_ADDR" json:"addr" usage:"address to listen on"`
+//
+// Supported field types are string, bool, int and time.Duration. Embedded
+// structs are walked too, so a program can add its own settings next to the
+// shared Server or Client ones.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// FileFlag is the flag that names the config file.
+	FileFlag = "config"
+	// FileEnv names the config file when FileFlag is not given.
+	FileEnv = "MCP_CONFIG"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting is one tagged field, reachable through the flag that sets it.
+type setting struct {
+	flag  string
+	env   string
+	key   string
+	value flag.Value
+}
+
+// Loader fills a tagged struct from a config file, the environment and flags.
+type Loader struct {
+	fs       *flag.FlagSet
+	file     string
+	settings []setting
+}
+
+// Register defines a flag on fs for every tagged field of the struct cfg
+// points to. The current field values become the defaults. It panics if cfg
+// is not a pointer to a struct or a tagged field has an unsupported type,
+// since both are programming errors.
+func Register(fs *flag.FlagSet, cfg any) *Loader {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("config: Register needs a pointer to a struct, got %T", cfg))
+	}
+	l := &Loader{fs: fs}
+	fs.StringVar(&l.file, FileFlag, "", "JSON config file (env "+FileEnv+")")
+	l.register(v.Elem())
+	return l
+}
+
+func (l *Loader) register(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			l.register(fv)
+			continue
+		}
+		name := field.Tag.Get("flag")
+		if name == "" {
+			continue
+		}
+		env := field.Tag.Get("env")
+		usage := field.Tag.Get("usage")
+		if env != "" {
+			usage += " (env " + env + ")"
+		}
+
+		switch {
+		case field.Type == durationType:
+			p := fv.Addr().Interface().(*time.Duration)
+			l.fs.DurationVar(p, name, *p, usage)
+		case field.Type.Kind() == reflect.String:
+			p := fv.Addr().Interface().(*string)
+			l.fs.StringVar(p, name, *p, usage)
+		case field.Type.Kind() == reflect.Bool:
+			p := fv.Addr().Interface().(*bool)
+			l.fs.BoolVar(p, name, *p, usage)
+		case field.Type.Kind() == reflect.Int:
+			p := fv.Addr().Interface().(*int)
+			l.fs.IntVar(p, name, *p, usage)
+		default:
+			panic(fmt.Sprintf("config: field %s has unsupported type %s", field.Name, field.Type))
+		}
+
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		l.settings = append(l.settings, setting{
+			flag:  name,
+			env:   env,
+			key:   key,
+			value: l.fs.Lookup(name).Value,
+		})
+	}
+}
+
+// Parse parses args and then applies the config file and the environment
+// underneath whatever flags were given explicitly.
+func (l *Loader) Parse(args []string) error {
+	if err := l.fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Flags were written straight into the struct; remember them so they
+	// can be put back on top of the file and the environment.
+	explicit := make(map[string]string)
+	l.fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+
+	path := l.file
+	if _, ok := explicit[FileFlag]; !ok {
+		path = os.Getenv(FileEnv)
+	}
+	if path != "" {
+		if err := l.loadFile(path); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range l.settings {
+		if s.env == "" {
+			continue
+		}
+		if raw, ok := os.LookupEnv(s.env); ok {
+			if err := s.value.Set(raw); err != nil {
+				return fmt.Errorf("environment %s=%q: %v", s.env, raw, err)
+			}
+		}
+	}
+
+	for _, s := range l.settings {
+		if raw, ok := explicit[s.flag]; ok {
+			if err := s.value.Set(raw); err != nil {
+				return fmt.Errorf("flag -%s: %v", s.flag, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadFile applies a JSON object of settings. Strings are used as they would
+// be on the command line, so durations are written like "30s". Keys that do
+// not name a setting are rejected to catch typos.
+func (l *Loader) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	byKey := make(map[string]setting, len(l.settings))
+	for _, s := range l.settings {
+		if s.key != "" {
+			byKey[s.key] = s
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("config file %s: unknown setting %q", path, key)
+		}
+		raw := values[key]
+		text := string(raw)
+		if bytes.HasPrefix(raw, []byte(`"`)) {
+			if err := json.Unmarshal(raw, &text); err != nil {
+				return fmt.Errorf("config file %s: %s: %w", path, key, err)
+			}
+		}
+		if err := s.value.Set(text); err != nil {
+			return fmt.Errorf("config file %s: %s: %v", path, key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+module github.com/duaraghav8/mcp-config
+
+go 1.24.3
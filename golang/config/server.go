@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Server holds the identity, transports and HTTP endpoints of an MCP server.
+type Server struct {
+	Name         string `flag:"name" env:"MCP_SERVER_NAME" json:"name" usage:"server name reported to clients"`
+	Version      string `flag:"version" env:"MCP_SERVER_VERSION" json:"version" usage:"server version reported to clients"`
+	Instructions string `flag:"instructions" env:"MCP_INSTRUCTIONS" json:"instructions" usage:"instructions returned to clients on initialize"`
+
+	Stdio      bool `flag:"stdio" env:"MCP_STDIO" json:"stdio" usage:"serve MCP over stdin/stdout"`
+	SSE        bool `flag:"sse" env:"MCP_SSE" json:"sse" usage:"serve the legacy HTTP+SSE transport"`
+	Streamable bool `flag:"streamable" env:"MCP_STREAMABLE" json:"streamable" usage:"serve streamable HTTP"`
+
+	Addr           string        `flag:"addr" env:"MCP_ADDR" json:"addr" usage:"address the HTTP transports listen on"`
+	BaseURL        string        `flag:"base-url" env:"MCP_BASE_URL" json:"base_url" usage:"public origin of the server, like https://mcp.example.com, used in the SSE endpoint event (default: relative URLs)"`
+	BasePath       string        `flag:"base-path" env:"MCP_BASE_PATH" json:"base_path" usage:"path prefix for every HTTP endpoint"`
+	StreamablePath string        `flag:"streamable-path" env:"MCP_STREAMABLE_PATH" json:"streamable_path" usage:"streamable HTTP endpoint"`
+	SSEPath        string        `flag:"sse-path" env:"MCP_SSE_PATH" json:"sse_path" usage:"SSE stream endpoint"`
+	MessagePath    string        `flag:"message-path" env:"MCP_MESSAGE_PATH" json:"message_path" usage:"SSE message endpoint"`
+	Heartbeat      time.Duration `flag:"heartbeat" env:"MCP_HEARTBEAT" json:"heartbeat" usage:"interval of keep-alive pings on open streams, 0 disables them"`
+
+	TLSCert string `flag:"tls-cert" env:"MCP_TLS_CERT" json:"tls_cert" usage:"TLS certificate file, enables HTTPS together with -tls-key"`
+	TLSKey  string `flag:"tls-key" env:"MCP_TLS_KEY" json:"tls_key" usage:"TLS private key file"`
+}
+
+// DefaultServer returns the settings the example server has always used.
+func DefaultServer() Server {
+	return Server{
+		Name:           "math-tools",
+		Version:        "1.0.0",
+		Streamable:     true,
+		Addr:           ":9000",
+		StreamablePath: "/mcp",
+		SSEPath:        "/sse",
+		MessagePath:    "/message",
+	}
+}
+
+// HTTP reports whether any HTTP transport is enabled.
+func (s *Server) HTTP() bool {
+	return s.SSE || s.Streamable
+}
+
+// TLS reports whether the HTTP transports are served over HTTPS.
+func (s *Server) TLS() bool {
+	return s.TLSCert != ""
+}
+
+// Endpoint returns path under the base path.
+func (s *Server) Endpoint(path string) string {
+	return s.BasePath + path
+}
+
+// Validate reports every invalid setting at once. HTTP settings are only
+// checked when an HTTP transport is enabled.
+func (s *Server) Validate() error {
+	var errs []error
+	if s.Name == "" {
+		errs = append(errs, errors.New("name must not be empty"))
+	}
+	if s.Version == "" {
+		errs = append(errs, errors.New("version must not be empty"))
+	}
+	if !s.Stdio && !s.HTTP() {
+		errs = append(errs, errors.New("no transport enabled, use -stdio, -sse or -streamable"))
+	}
+	if !s.HTTP() {
+		return errors.Join(errs...)
+	}
+
+	if err := checkAddr("addr", s.Addr); err != nil {
+		errs = append(errs, err)
+	}
+	if s.BaseURL != "" {
+		if err := checkOrigin("base-url", s.BaseURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if s.BasePath != "" {
+		if err := checkPath("base-path", s.BasePath); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	paths := make(map[string]string)
+	for _, p := range []struct {
+		name, path string
+		enabled    bool
+	}{
+		{"streamable-path", s.StreamablePath, s.Streamable},
+		{"sse-path", s.SSEPath, s.SSE},
+		{"message-path", s.MessagePath, s.SSE},
+	} {
+		if !p.enabled {
+			continue
+		}
+		if err := checkPath(p.name, p.path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if other, ok := paths[p.path]; ok {
+			errs = append(errs, fmt.Errorf("%s and %s are both %q", other, p.name, p.path))
+		}
+		paths[p.path] = p.name
+	}
+
+	if s.Heartbeat < 0 {
+		errs = append(errs, fmt.Errorf("heartbeat %s: must not be negative", s.Heartbeat))
+	}
+
+	switch {
+	case s.TLSCert == "" && s.TLSKey == "":
+	case s.TLSCert == "" || s.TLSKey == "":
+		errs = append(errs, errors.New("tls-cert and tls-key must be given together"))
+	default:
+		if err := checkFile("tls-cert", s.TLSCert); err != nil {
+			errs = append(errs, err)
+		}
+		if err := checkFile("tls-key", s.TLSKey); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// checkAddr accepts a host:port listen address; the host may be empty.
+func checkAddr(name, addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("%s %q: %v", name, addr, err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("%s %q: port must be a number between 0 and 65535", name, addr)
+	}
+	return nil
+}
+
+// CheckURL accepts an absolute http or https URL. name is the setting the
+// URL came from and starts the error message.
+func CheckURL(name, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%s %q: %v", name, raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%s %q: scheme must be http or https", name, raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%s %q: missing host", name, raw)
+	}
+	return nil
+}
+
+// checkOrigin accepts an http or https URL without path, query or fragment.
+// Paths belong in the base path setting.
+func checkOrigin(name, raw string) error {
+	if err := CheckURL(name, raw); err != nil {
+		return err
+	}
+	u, _ := url.Parse(raw)
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%s %q: must be scheme and host only, set paths with base-path", name, raw)
+	}
+	return nil
+}
+
+// checkPath accepts an absolute URL path without a trailing slash.
+func checkPath(name, path string) error {
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("%s %q: must start with /", name, path)
+	}
+	if len(path) > 1 && strings.HasSuffix(path, "/") {
+		return fmt.Errorf("%s %q: must not end with /", name, path)
+	}
+	return nil
+}
+
+// checkFile accepts a path to a readable regular file.
+func checkFile(name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s %q: not a regular file", name, path)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Package callback receives OAuth authorization responses: the redirect
+// that sends the user's browser back to a loopback address with a code, or
+// an error, once they have approved or refused access.
+//
+// One Listener serves every authorization a process makes. Each request is
+// announced with Expect before the browser is sent off, and its state is
+// good for a single redirect, so a stale or replayed response is turned
+// away rather than mistaken for the current one.
+package callback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Error is an error response from the authorization server (RFC 6749
+// section 4.1.2.1), such as access_denied when the user refuses.
+type Error struct {
+	Code        string
+	Description string
+	URI         string
+}
+
+func (e *Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("authorization server error: %s - %s", e.Code, e.Description)
+	}
+	return "authorization server error: " + e.Code
+}
+
+// result is what the redirect brought for one request.
+type result struct {
+	code string
+	err  error
+}
+
+// Listener is a local HTTP server for authorization redirects.
+type Listener struct {
+	server      *http.Server
+	redirectURI string
+
+	mu      sync.Mutex
+	pending map[string]chan result
+}
+
+// Listen starts a listener on addr that takes redirects to path. A port of
+// 0 in addr picks a free port; RedirectURI reports the one in use.
+func Listen(addr, path string) (*Listener, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("callback address %q: %w", addr, err)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("callback address %q: %w", addr, err)
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	l := &Listener{
+		redirectURI: "http://" + net.JoinHostPort(host, strconv.Itoa(port)) + path,
+		pending:     make(map[string]chan result),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+path, l.handle)
+	l.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := l.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("OAuth callback server error: %v", err)
+		}
+	}()
+	return l, nil
+}
+
+// RedirectURI is the address to register and to send in authorization
+// requests.
+func (l *Listener) RedirectURI() string {
+	return l.redirectURI
+}
+
+// Close stops the listener. Requests still waiting fail.
+func (l *Listener) Close() error {
+	return l.server.Close()
+}
+
+// Request is an authorization request waiting for its redirect.
+type Request struct {
+	l      *Listener
+	state  string
+	result chan result
+}
+
+// Expect announces an authorization request with the given state. Call it
+// before sending the user to the authorization server, and Wait for the
+// code.
+func (l *Listener) Expect(state string) *Request {
+	r := &Request{l: l, state: state, result: make(chan result, 1)}
+	l.mu.Lock()
+	l.pending[state] = r.result
+	l.mu.Unlock()
+	return r
+}
+
+// Wait returns the authorization code from the redirect, or the error the
+// authorization server sent instead, or ctx's error if it ends first. The
+// state is forgotten when Wait returns.
+func (r *Request) Wait(ctx context.Context) (string, error) {
+	defer r.cancel()
+	select {
+	case res := <-r.result:
+		return res.code, res.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("waiting for the authorization redirect: %w", ctx.Err())
+	}
+}
+
+func (r *Request) cancel() {
+	r.l.mu.Lock()
+	defer r.l.mu.Unlock()
+	if r.l.pending[r.state] == r.result {
+		delete(r.l.pending, r.state)
+	}
+}
+
+// take removes and returns the request waiting for state, so a state works
+// once.
+func (l *Listener) take(state string) (chan result, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ch, ok := l.pending[state]
+	delete(l.pending, state)
+	return ch, ok
+}
+
+func (l *Listener) handle(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	ch, ok := l.take(q.Get("state"))
+	if !ok {
+		page(w, http.StatusBadRequest, false,
+			"This authorization response is not expected: it is out of date or has been used already. Start again from the application.")
+		return
+	}
+
+	var res result
+	switch {
+	case q.Get("error") != "":
+		res.err = &Error{Code: q.Get("error"), Description: q.Get("error_description"), URI: q.Get("error_uri")}
+	case q.Get("code") == "":
+		res.err = errors.New("the authorization response has no code")
+	default:
+		res.code = q.Get("code")
+	}
+	ch <- res
+
+	if res.err != nil {
+		page(w, http.StatusOK, false, res.err.Error()+". Return to the application for details.")
+		return
+	}
+	page(w, http.StatusOK, true, "You can now close this window and return to the application.")
+}
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+	<head><title>{{.Title}}</title></head>
+	<body>
+		<h1>{{.Title}}</h1>
+		<p>{{.Message}}</p>
+		{{if .OK}}<script>window.close();</script>{{end}}
+	</body>
+</html>
+`))
+
+// page tells the user in the browser how it went.
+func page(w http.ResponseWriter, status int, ok bool, message string) {
+	title := "Authorization Successful"
+	if !ok {
+		title = "Authorization Failed"
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	err := pageTemplate.Execute(w, struct {
+		Title, Message string
+		OK             bool
+	}{title, message, ok})
+	if err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
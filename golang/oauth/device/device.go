@@ -0,0 +1,215 @@
+// Package device implements the OAuth 2.0 device authorization grant (RFC
+// 8628), which lets a program without a browser get a token: the user
+// approves the request on another device by entering a short code, while
+// the program polls the token endpoint.
+package device
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+// GrantType is the grant type of device access token requests.
+const GrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// Discover returns the device authorization endpoint that the authorization
+// server issuer advertises in its metadata, or "" when it advertises none.
+func Discover(ctx context.Context, hc *http.Client, issuer string) (string, error) {
+	if hc == nil {
+		hc = defaultClient
+	}
+	u, err := url.Parse(issuer)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("issuer %q: not an absolute URL", issuer)
+	}
+	// RFC 8414 puts the well-known path before the issuer's own path; some
+	// servers only serve it after, as OpenID Connect does.
+	path := strings.TrimSuffix(u.Path, "/")
+	root := u.Scheme + "://" + u.Host
+	var candidates []string
+	for _, name := range []string{"oauth-authorization-server", "openid-configuration"} {
+		candidates = append(candidates, root+"/.well-known/"+name+path)
+		if path != "" {
+			candidates = append(candidates, root+path+"/.well-known/"+name)
+		}
+	}
+
+	var errs []error
+	for _, metadataURL := range candidates {
+		var metadata struct {
+			DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+		}
+		found, err := getJSON(ctx, hc, metadataURL, &metadata)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if found {
+			return metadata.DeviceAuthorizationEndpoint, nil
+		}
+	}
+	return "", errors.Join(errs...)
+}
+
+// getJSON decodes the document at u into v, reporting false if there is
+// none.
+func getJSON(ctx context.Context, hc *http.Client, u string, v any) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := hc.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return false, fmt.Errorf("%s: %w", u, err)
+	}
+	return true, nil
+}
+
+var defaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// Config says where and as whom to ask for a token.
+type Config struct {
+	// HTTPClient sends the requests; a client with a 30 second timeout by
+	// default.
+	HTTPClient *http.Client
+	// Endpoint is the device authorization endpoint, as found by Discover.
+	Endpoint string
+	// TokenEndpoint is the authorization server's token endpoint.
+	TokenEndpoint string
+	ClientID      string
+	ClientSecret  string
+	Scopes        []string
+}
+
+// Code is the authorization server's answer to a device authorization
+// request: what to show the user, and what to poll with.
+type Code struct {
+	DeviceCode string `json:"device_code"`
+	// UserCode is what the user enters at VerificationURI.
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	// VerificationURIComplete, if set, carries the user code, so the user
+	// need not type it.
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	// ExpiresIn is how many seconds the codes are valid for.
+	ExpiresIn int `json:"expires_in"`
+	// Interval is how many seconds to wait between polls.
+	Interval int `json:"interval,omitempty"`
+}
+
+// Start asks for a device code and user code.
+func (c *Config) Start(ctx context.Context) (*Code, error) {
+	form := url.Values{"client_id": {c.ClientID}}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	var code Code
+	if err := c.post(ctx, c.Endpoint, form, &code); err != nil {
+		return nil, fmt.Errorf("device authorization request: %w", err)
+	}
+	if code.DeviceCode == "" || code.UserCode == "" || code.VerificationURI == "" {
+		return nil, errors.New("device authorization request: incomplete response")
+	}
+	return &code, nil
+}
+
+// Wait polls for the token until the user approves or denies the request,
+// the codes expire or ctx ends.
+func (c *Config) Wait(ctx context.Context, code *Code) (*transport.Token, error) {
+	interval := 5 * time.Second
+	if code.Interval > 0 {
+		interval = time.Duration(code.Interval) * time.Second
+	}
+	if code.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(code.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+	form := url.Values{
+		"grant_type":  {GrantType},
+		"device_code": {code.DeviceCode},
+		"client_id":   {c.ClientID},
+	}
+
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, errors.New("the device code expired before the request was approved")
+			}
+			return nil, ctx.Err()
+		}
+
+		var token transport.Token
+		err := c.post(ctx, c.TokenEndpoint, form, &token)
+		var oauthErr transport.OAuthError
+		switch {
+		case err == nil:
+			if token.ExpiresIn > 0 {
+				token.ExpiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+			}
+			return &token, nil
+		case errors.As(err, &oauthErr) && oauthErr.ErrorCode == "authorization_pending":
+		case errors.As(err, &oauthErr) && oauthErr.ErrorCode == "slow_down":
+			interval += 5 * time.Second
+		default:
+			// access_denied, expired_token and anything unexpected
+			return nil, fmt.Errorf("device access token request: %w", err)
+		}
+	}
+}
+
+// post sends a form and decodes the JSON reply into v. An OAuth error reply
+// is returned as a transport.OAuthError.
+func (c *Config) post(ctx context.Context, endpoint string, form url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = defaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr transport.OAuthError
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.ErrorCode != "" {
+			return oauthErr
+		}
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
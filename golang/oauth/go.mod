@@ -2,7 +2,10 @@ module github.com/duaraghav8/mcp-oauth
 
 go 1.25.0
 
-require github.com/mark3labs/mcp-go v0.43.0
+require (
+	github.com/duaraghav8/mcp-config v0.0.0
+	github.com/mark3labs/mcp-go v0.43.0
+)
 
 require (
 	github.com/bahlo/generic-list-go v0.2.0 // indirect
@@ -15,3 +18,5 @@ require (
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/duaraghav8/mcp-config => ../config
@@ -1,38 +1,118 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
+	"net/url"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
-
+	"strings"
+	"time"
+
+	"github.com/duaraghav8/mcp-config"
+	"github.com/duaraghav8/mcp-oauth/callback"
+	"github.com/duaraghav8/mcp-oauth/device"
+	"github.com/duaraghav8/mcp-oauth/refresh"
+	"github.com/duaraghav8/mcp-oauth/tokenstore"
 	"github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-const (
-	// Replace with your MCP server URL
-	serverURL = "https://huggingface.co/mcp?login"
-	//serverURL = "https://mcp.deepwiki.com/mcp"
-	// Use a localhost redirect URI for this example
-	redirectURI = "http://localhost:8085/oauth/callback"
-)
+// callbackPath is where the authorization server sends the browser back
+// to.
+const callbackPath = "/oauth/callback"
+
+// keyEnv holds the base64 key that encrypts the token file. It is read from
+// the environment only, so the key never shows up in a process listing.
+const keyEnv = "MCP_OAUTH_KEY"
+
+// options are the example's settings: the server, how to authorize, and
+// where tokens are kept between runs.
+type options struct {
+	config.Client
+	TokenFile    string        `flag:"token-file" env:"MCP_OAUTH_TOKEN_FILE" json:"token_file" usage:"encrypted file to keep OAuth tokens in"`
+	Headless     bool          `flag:"headless" env:"MCP_OAUTH_HEADLESS" json:"headless" usage:"authorize without a local browser: with the device flow if the server offers it, otherwise by pasting the URL the browser was sent back to"`
+	KeyFile      string        `flag:"key-file" env:"MCP_OAUTH_KEY_FILE" json:"key_file" usage:"file holding the token file's key, created if missing; ignored when MCP_OAUTH_KEY is set"`
+	CallbackAddr string        `flag:"callback-addr" env:"MCP_OAUTH_CALLBACK_ADDR" json:"callback_addr" usage:"local address to receive the authorization redirect on; port 0 picks a free port"`
+	AuthTimeout  time.Duration `flag:"auth-timeout" env:"MCP_OAUTH_AUTH_TIMEOUT" json:"auth_timeout" usage:"how long to wait for the user to authorize"`
+}
+
+// serverURL is the MCP server to authorize against, set from -url or
+// MCP_SERVER_URL, e.g. https://mcp.deepwiki.com/mcp.
+var serverURL string
+
+// headless is set from -headless or MCP_OAUTH_HEADLESS.
+var headless bool
+
+// authTimeout bounds each authorization, set from -auth-timeout.
+var authTimeout time.Duration
+
+// callbacks receives the authorization redirects of every authorization
+// this process makes.
+var callbacks *callback.Listener
 
 func main() {
-	// Create a token store to persist tokens
-	tokenStore := client.NewMemoryTokenStore()
+	dir, err := os.UserConfigDir()
+	if err == nil {
+		dir = filepath.Join(dir, "mcp-oauth")
+	}
+	opts := options{
+		Client:    config.Client{URL: "https://huggingface.co/mcp?login"},
+		TokenFile: filepath.Join(dir, "tokens"),
+		KeyFile:   filepath.Join(dir, "key"),
+
+		CallbackAddr: "localhost:8085",
+		AuthTimeout:  5 * time.Minute,
+	}
+	if err := config.Register(flag.CommandLine, &opts).Parse(os.Args[1:]); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := opts.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	serverURL = opts.URL
+	headless = opts.Headless
+	authTimeout = opts.AuthTimeout
+
+	// Listen for authorization redirects before creating clients, which
+	// register the listener's address as their redirect URI
+	callbacks, err = callback.Listen(opts.CallbackAddr, callbackPath)
+	if err != nil {
+		log.Fatalf("Failed to start callback server: %v", err)
+	}
+	defer callbacks.Close()
+
+	// Keep tokens in an encrypted file, so that the next run can use them
+	// without going through the browser again
+	key, err := tokenstore.LoadKey(keyEnv, opts.KeyFile)
+	if err != nil {
+		log.Fatalf("Failed to load token key: %v", err)
+	}
+	tokenFile, err := tokenstore.Open(opts.TokenFile, key)
+	if err != nil {
+		log.Fatalf("Failed to open token file: %v", err)
+	}
+	tokenStore, err := tokenFile.Server(context.Background(), serverURL)
+	if err != nil {
+		log.Fatalf("Failed to read token file: %v", err)
+	}
 
 	c := createConn(tokenStore)
 	//defer c.Close()
+	refresher := keepFresh(c, tokenStore)
 
 	callToolReq := mcp.CallToolRequest{}
 	callToolReq.Params.Name = "hf_whoami"
 	callToolReq.Params.Arguments = map[string]any{}
 
-	resp, err := c.CallTool(context.Background(), callToolReq)
+	resp, err := callTool(context.Background(), c, refresher, callToolReq)
 	if err != nil {
 		log.Fatalf("Failed to call tool: %v", err)
 	}
@@ -50,7 +130,7 @@ func main() {
 		"limit": 3,
 	}
 
-	resp, err = c.CallTool(context.Background(), callToolReq)
+	resp, err = callTool(context.Background(), c, refresher, callToolReq)
 	if err != nil {
 		log.Fatalf("Failed to call tool space_search: %v", err)
 	}
@@ -60,6 +140,7 @@ func main() {
 
 	fmt.Println("-------------------------------")
 	fmt.Println("Closing current connection...")
+	refresher.Stop()
 	c.Close()
 
 	fmt.Println("opening new connection...")
@@ -71,8 +152,10 @@ func main() {
 
 	nc := createConn(tokenStore)
 	defer nc.Close()
+	nrefresher := keepFresh(nc, tokenStore)
+	defer nrefresher.Stop()
 
-	resp, err = nc.CallTool(context.Background(), callToolReq)
+	resp, err = callTool(context.Background(), nc, nrefresher, callToolReq)
 	if err != nil {
 		log.Fatalf("Failed to call tool space_search: %v", err)
 	}
@@ -83,10 +166,14 @@ func main() {
 	fmt.Println("All done!")
 }
 
-func createConn(tokenStore transport.TokenStore) *client.Client {
-	// Create OAuth configuration
+func createConn(tokenStore *tokenstore.Store) *client.Client {
+	// Create OAuth configuration, reusing the client registered on an
+	// earlier run if there is one
+	registered := tokenStore.Client()
 	oauthConfig := client.OAuthConfig{
-		RedirectURI: redirectURI,
+		ClientID:     registered.ID,
+		ClientSecret: registered.Secret,
+		RedirectURI:  callbacks.RedirectURI(),
 		//Scopes:       []string{"openid", "profile", "read-mcp"},
 		TokenStore:  tokenStore,
 		PKCEEnabled: true, // Enable PKCE for public clients
@@ -99,7 +186,7 @@ func createConn(tokenStore transport.TokenStore) *client.Client {
 
 	// Start the client
 	if err := c.Start(context.Background()); err != nil {
-		maybeAuthorize(err)
+		maybeAuthorize(err, tokenStore)
 		if err = c.Start(context.Background()); err != nil {
 			log.Fatalf("Failed to start client: %v", err)
 		}
@@ -125,7 +212,7 @@ func createConn(tokenStore transport.TokenStore) *client.Client {
 	fmt.Println(err)
 
 	if err != nil {
-		maybeAuthorize(err)
+		maybeAuthorize(err, tokenStore)
 		result, err = c.Initialize(context.Background(), initReq)
 		if err != nil {
 			log.Fatalf("Failed to initialize client: %v", err)
@@ -139,115 +226,274 @@ func createConn(tokenStore transport.TokenStore) *client.Client {
 	return c
 }
 
-func maybeAuthorize(err error) {
+// maybeAuthorize runs the authorization flow if err says that it is
+// needed, and exits if the flow fails.
+func maybeAuthorize(err error, tokenStore *tokenstore.Store) {
 	// Check if we need OAuth authorization
-	if client.IsOAuthAuthorizationRequiredError(err) {
-		fmt.Println("OAuth authorization required. Starting authorization flow...")
-
-		// Get the OAuth handler from the error
-		oauthHandler := client.GetOAuthHandler(err)
+	if !client.IsOAuthAuthorizationRequiredError(err) {
+		return
+	}
+	fmt.Println("OAuth authorization required. Starting authorization flow...")
+	if err := authorize(context.Background(), client.GetOAuthHandler(err), tokenStore); err != nil {
+		log.Fatalf("Authorization failed: %v", err)
+	}
+}
 
-		// Start a local server to handle the OAuth callback
-		callbackChan := make(chan map[string]string)
-		server := startCallbackServer(callbackChan)
-		defer server.Close()
+// authorize gets a token for oauthHandler's client, registering the client
+// first if need be. The handler saves the token to tokenStore.
+func authorize(ctx context.Context, oauthHandler *transport.OAuthHandler, tokenStore *tokenstore.Store) error {
+	ctx, cancel := context.WithTimeout(ctx, authTimeout)
+	defer cancel()
+	err := authorizeClient(ctx, oauthHandler, tokenStore)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("gave up after %s: %w", authTimeout, err)
+	}
+	return err
+}
 
-		// Generate PKCE code verifier and challenge
-		codeVerifier, err := client.GenerateCodeVerifier()
+func authorizeClient(ctx context.Context, oauthHandler *transport.OAuthHandler, tokenStore *tokenstore.Store) error {
+	// A client registered with another redirect URI, as happens when the
+	// callback port is picked afresh each run, must register again
+	registered := tokenStore.Client()
+	moved := registered.RedirectURI != "" && registered.RedirectURI != callbacks.RedirectURI()
+	if oauthHandler.GetClientID() == "" || moved {
+		err := oauthHandler.RegisterClient(ctx, "mcp-go-oauth-example")
 		if err != nil {
-			log.Fatalf("Failed to generate code verifier: %v", err)
+			return fmt.Errorf("register client: %w", err)
 		}
-		codeChallenge := client.GenerateCodeChallenge(codeVerifier)
-
-		// Generate state parameter
-		state, err := client.GenerateState()
+		// Remember the registration, and file the token that follows
+		// under it
+		err = tokenStore.SetClient(ctx, tokenstore.Client{
+			ID:          oauthHandler.GetClientID(),
+			Secret:      oauthHandler.GetClientSecret(),
+			RedirectURI: callbacks.RedirectURI(),
+		})
 		if err != nil {
-			log.Fatalf("Failed to generate state: %v", err)
+			return fmt.Errorf("save client registration: %w", err)
 		}
+	}
+	if headless {
+		return authorizeHeadless(ctx, oauthHandler, tokenStore)
+	}
+	return authorizeBrowser(ctx, oauthHandler)
+}
 
-		if oauthHandler.GetClientID() == "" {
-			err = oauthHandler.RegisterClient(context.Background(), "mcp-go-oauth-example")
-			if err != nil {
-				log.Fatalf("Failed to register client: %v", err)
-			}
-		}
+// authorizeBrowser has the user approve access in the browser and exchanges
+// the code it gets back for a token.
+func authorizeBrowser(ctx context.Context, oauthHandler *transport.OAuthHandler) error {
+	// Generate PKCE code verifier and challenge
+	codeVerifier, err := client.GenerateCodeVerifier()
+	if err != nil {
+		return fmt.Errorf("generate code verifier: %w", err)
+	}
+	codeChallenge := client.GenerateCodeChallenge(codeVerifier)
 
-		// Get the authorization URL
-		authURL, err := oauthHandler.GetAuthorizationURL(context.Background(), state, codeChallenge)
-		if err != nil {
-			log.Fatalf("Failed to get authorization URL: %v", err)
-		}
+	// Generate state parameter
+	state, err := client.GenerateState()
+	if err != nil {
+		return fmt.Errorf("generate state: %w", err)
+	}
 
-		// Open the browser to the authorization URL
-		fmt.Printf("Opening browser to: %s\n", authURL)
-		openBrowser(authURL)
+	// Get the authorization URL
+	authURL, err := oauthHandler.GetAuthorizationURL(ctx, state, codeChallenge)
+	if err != nil {
+		return fmt.Errorf("get authorization URL: %w", err)
+	}
 
-		// Wait for the callback
-		fmt.Println("Waiting for authorization callback...")
-		params := <-callbackChan
+	// Expect the callback, which the listener only accepts with this
+	// state, and only once
+	request := callbacks.Expect(state)
 
-		// Verify state parameter
-		if params["state"] != state {
-			log.Fatalf("State mismatch: expected %s, got %s", state, params["state"])
-		}
+	// Open the browser to the authorization URL
+	fmt.Printf("Opening browser to: %s\n", authURL)
+	openBrowser(authURL)
 
-		// Exchange the authorization code for a token
-		code := params["code"]
-		if code == "" {
-			log.Fatalf("No authorization code received")
-		}
+	// Wait for the callback
+	fmt.Println("Waiting for authorization callback...")
+	code, err := request.Wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Exchange the authorization code for a token
+
+	fmt.Println("Exchanging authorization code for token...")
+	err = oauthHandler.ProcessAuthorizationResponse(ctx, code, state, codeVerifier)
+	if err != nil {
+		return fmt.Errorf("process authorization response: %w", err)
+	}
+
+	fmt.Println("Authorization successful!")
+	return nil
+}
 
-		fmt.Println("Exchanging authorization code for token...")
-		err = oauthHandler.ProcessAuthorizationResponse(context.Background(), code, state, codeVerifier)
+// authorizeHeadless gets a token without a browser on this machine: with
+// the device authorization grant when the authorization server offers it,
+// and otherwise by having the user paste where the browser was sent.
+func authorizeHeadless(ctx context.Context, oauthHandler *transport.OAuthHandler, tokenStore *tokenstore.Store) error {
+	metadata, err := oauthHandler.GetServerMetadata(ctx)
+	if err != nil {
+		return fmt.Errorf("get server metadata: %w", err)
+	}
+	var endpoint string
+	if metadata.Issuer != "" {
+		endpoint, err = device.Discover(ctx, nil, metadata.Issuer)
 		if err != nil {
-			log.Fatalf("Failed to process authorization response: %v", err)
+			log.Printf("Failed to look for device authorization: %v", err)
 		}
+	}
+	if endpoint == "" {
+		return authorizePaste(ctx, oauthHandler)
+	}
 
-		fmt.Println("Authorization successful!")
+	err = authorizeDevice(ctx, oauthHandler, tokenStore, device.Config{
+		Endpoint:      endpoint,
+		TokenEndpoint: metadata.TokenEndpoint,
+		ClientID:      oauthHandler.GetClientID(),
+		ClientSecret:  oauthHandler.GetClientSecret(),
+	})
+	// A dynamically registered client may not be allowed the device grant
+	var oauthErr transport.OAuthError
+	if errors.As(err, &oauthErr) && oauthErr.ErrorCode == "unauthorized_client" {
+		fmt.Println("This client may not use device authorization.")
+		return authorizePaste(ctx, oauthHandler)
 	}
+	return err
 }
 
-// startCallbackServer starts a local HTTP server to handle the OAuth callback
-func startCallbackServer(callbackChan chan<- map[string]string) *http.Server {
-	server := &http.Server{
-		Addr: ":8085",
+// authorizeDevice runs the device authorization grant and saves the token
+// to tokenStore.
+func authorizeDevice(ctx context.Context, oauthHandler *transport.OAuthHandler, tokenStore *tokenstore.Store, cfg device.Config) error {
+	code, err := cfg.Start(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("To authorize, visit %s and enter the code %s\n", code.VerificationURI, code.UserCode)
+	if code.VerificationURIComplete != "" {
+		fmt.Printf("or open %s\n", code.VerificationURIComplete)
+	}
+	fmt.Println("Waiting for approval...")
+	token, err := cfg.Wait(ctx, code)
+	if err != nil {
+		return err
 	}
+	if err := tokenStore.SaveToken(ctx, token); err != nil {
+		return fmt.Errorf("save token: %w", err)
+	}
+	fmt.Println("Authorization successful!")
+	return nil
+}
 
-	http.HandleFunc("/oauth/callback", func(w http.ResponseWriter, r *http.Request) {
-		// Extract query parameters
-		params := make(map[string]string)
-		for key, values := range r.URL.Query() {
-			if len(values) > 0 {
-				params[key] = values[0]
-			}
-		}
+// authorizePaste has the user approve access in a browser anywhere. The
+// browser is then sent to the redirect URI, which need not load: the user
+// pastes its address, or only the code in it, back here.
+func authorizePaste(ctx context.Context, oauthHandler *transport.OAuthHandler) error {
+	codeVerifier, err := client.GenerateCodeVerifier()
+	if err != nil {
+		return fmt.Errorf("generate code verifier: %w", err)
+	}
+	codeChallenge := client.GenerateCodeChallenge(codeVerifier)
+	state, err := client.GenerateState()
+	if err != nil {
+		return fmt.Errorf("generate state: %w", err)
+	}
+	authURL, err := oauthHandler.GetAuthorizationURL(ctx, state, codeChallenge)
+	if err != nil {
+		return fmt.Errorf("get authorization URL: %w", err)
+	}
 
-		// Send parameters to the channel
-		callbackChan <- params
-
-		// Respond to the user
-		w.Header().Set("Content-Type", "text/html")
-		_, err := w.Write([]byte(`
-			<html>
-				<body>
-					<h1>Authorization Successful</h1>
-					<p>You can now close this window and return to the application.</p>
-					<script>window.close();</script>
-				</body>
-			</html>
-		`))
-		if err != nil {
-			log.Printf("Error writing response: %v", err)
-		}
-	})
+	fmt.Printf("Open this URL in a browser on any machine:\n\n  %s\n\n", authURL)
+	fmt.Println("After you approve, the browser is sent to " + callbacks.RedirectURI() + ", which may fail to load.")
+	fmt.Print("Paste the address it was sent to, or the code in it: ")
+	line, err := readLine(ctx)
+	if err != nil {
+		return err
+	}
+	code, gotState, err := parsePasted(line)
+	if err != nil {
+		return err
+	}
+	if gotState == "" {
+		// Only the code was pasted, so there is no state to check
+		gotState = state
+	}
+
+	fmt.Println("Exchanging authorization code for token...")
+	err = oauthHandler.ProcessAuthorizationResponse(ctx, code, gotState, codeVerifier)
+	if err != nil {
+		return fmt.Errorf("process authorization response: %w", err)
+	}
+	fmt.Println("Authorization successful!")
+	return nil
+}
+
+// parsePasted takes the code, and the state if there is one, from a pasted
+// redirect URL or a bare code.
+func parsePasted(s string) (code, state string, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", "", errors.New("nothing was pasted")
+	}
+	if !strings.Contains(s, "?") {
+		return s, "", nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", "", fmt.Errorf("pasted address: %w", err)
+	}
+	q := u.Query()
+	if q.Get("error") != "" {
+		return "", "", &callback.Error{Code: q.Get("error"), Description: q.Get("error_description"), URI: q.Get("error_uri")}
+	}
+	if q.Get("code") == "" {
+		return "", "", errors.New("the pasted address has no code in it")
+	}
+	return q.Get("code"), q.Get("state"), nil
+}
 
+// readLine reads a line from stdin, giving up when ctx ends.
+func readLine(ctx context.Context) (string, error) {
+	lines := make(chan string, 1)
+	errs := make(chan error, 1)
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("HTTP server error: %v", err)
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			errs <- fmt.Errorf("read from stdin: %w", err)
+			return
 		}
+		lines <- line
 	}()
+	select {
+	case line := <-lines:
+		return line, nil
+	case err := <-errs:
+		return "", err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// keepFresh refreshes c's token in the background before it expires, and
+// goes through the browser again when it cannot be refreshed.
+func keepFresh(c *client.Client, tokenStore *tokenstore.Store) *refresh.Refresher {
+	oauthHandler := c.GetTransport().(*transport.StreamableHTTP).GetOAuthHandler()
+	return refresh.Start(oauthHandler, tokenStore, refresh.Options{
+		Reauthorize: func(ctx context.Context, h *transport.OAuthHandler) error {
+			fmt.Println("OAuth token cannot be refreshed. Starting authorization flow...")
+			return authorize(ctx, h, tokenStore)
+		},
+	})
+}
 
-	return server
+// callTool calls a tool, getting a new token and calling it again if the
+// server no longer accepts the token it was sent with.
+func callTool(ctx context.Context, c *client.Client, r *refresh.Refresher, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var resp *mcp.CallToolResult
+	err := r.Do(ctx, func() (err error) {
+		resp, err = c.CallTool(ctx, req)
+		return err
+	})
+	return resp, err
 }
 
 // openBrowser opens the default browser to the specified URL
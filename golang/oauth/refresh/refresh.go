@@ -0,0 +1,279 @@
+// Package refresh keeps an OAuth client's access token valid for as long as
+// the client runs.
+//
+// mcp-go refreshes a token only once it has expired, while a request is
+// waiting, and gives up with an authorization error when that fails. A
+// Refresher instead refreshes in the background shortly before the token
+// expires, so requests do not notice. When the refresh token is missing or
+// rejected it falls back to the Reauthorize hook, which runs the interactive
+// flow again; in the background it tries that once, and after a failure
+// leaves it to Do. Do covers the rest: a request the server still turns
+// away, say because the token was revoked, is retried once with a new token.
+//
+// Processes sharing a token store that implements LockRefresh, such as
+// tokenstore.Store, take turns to renew, so a refresh token one of them has
+// just used up is not sent again by another.
+package refresh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+// Options tune a Refresher. The zero value is usable, apart from having no
+// way to reauthorize.
+type Options struct {
+	// Margin is how long before expiry to refresh: 1 minute by default, and
+	// never more than half the token's lifetime.
+	Margin time.Duration
+	// RetryDelay is the wait after a refresh fails for a reason that may
+	// pass, such as the network: 5 seconds by default.
+	RetryDelay time.Duration
+	// Reauthorize gets a new token interactively and saves it through the
+	// handler, when refreshing cannot. Without it an expired token that
+	// cannot be refreshed stays expired. The background refresher does not
+	// call it again after it fails, so as not to keep prompting a user who
+	// is not there; Do still does.
+	Reauthorize func(ctx context.Context, h *transport.OAuthHandler) error
+	// Logf reports refreshes and failures; log.Printf by default.
+	Logf func(format string, args ...any)
+}
+
+// refreshLocker is implemented by token stores shared between processes.
+type refreshLocker interface {
+	LockRefresh(ctx context.Context) (unlock func(), err error)
+}
+
+// errReauthorizeLater is returned by a background renewal that needs to
+// reauthorize after reauthorizing failed once.
+var errReauthorizeLater = errors.New("the token needs reauthorizing, which is left to the next request that fails")
+
+// idle is how often a token without an expiry time, or no token at all, is
+// looked at again, in case another process saved one.
+const idle = time.Minute
+
+// Refresher refreshes the token of one OAuth handler. Refreshed tokens are
+// saved to the token store by the handler.
+type Refresher struct {
+	handler *transport.OAuthHandler
+	store   transport.TokenStore
+	opts    Options
+
+	// mu lets one renewal run at a time; renewed is when the last one
+	// succeeded. reauthFailed is set when reauthorizing in the background
+	// failed, and cleared when a renewal succeeds.
+	mu           sync.Mutex
+	renewed      time.Time
+	reauthFailed bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start begins refreshing the token that handler keeps in store, which
+// must be the handler's token store. Call Stop when done.
+func Start(handler *transport.OAuthHandler, store transport.TokenStore, opts Options) *Refresher {
+	if opts.Margin <= 0 {
+		opts.Margin = time.Minute
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = 5 * time.Second
+	}
+	if opts.Logf == nil {
+		opts.Logf = log.Printf
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Refresher{
+		handler: handler,
+		store:   store,
+		opts:    opts,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go r.run(ctx)
+	return r
+}
+
+// Stop ends background refreshing and waits for a refresh in progress.
+func (r *Refresher) Stop() {
+	r.cancel()
+	<-r.done
+}
+
+// Do runs fn, which sends a request with the handler's client. If the
+// server turns the request away for want of authorization, Do gets a new
+// token, by refreshing or reauthorizing, and runs fn once more.
+func (r *Refresher) Do(ctx context.Context, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	if !client.IsOAuthAuthorizationRequiredError(err) {
+		return err
+	}
+	if rerr := r.renew(ctx, start); rerr != nil {
+		return fmt.Errorf("%w (getting a new token: %v)", err, rerr)
+	}
+	return fn()
+}
+
+func (r *Refresher) run(ctx context.Context) {
+	defer close(r.done)
+	delay := r.opts.RetryDelay
+	deferred := false
+	for {
+		wait := idle
+		token, err := r.store.GetToken(ctx)
+		if err == nil && !token.ExpiresAt.IsZero() {
+			wait = time.Until(r.due(token))
+		}
+		if wait > 0 {
+			if !sleep(ctx, wait) {
+				return
+			}
+			if wait == idle {
+				continue
+			}
+		}
+
+		if err := r.renew(ctx, time.Time{}); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, errReauthorizeLater) {
+				if !deferred {
+					r.opts.Logf("oauth: %v", err)
+					deferred = true
+				}
+				if !sleep(ctx, idle) {
+					return
+				}
+				continue
+			}
+			r.opts.Logf("oauth: could not get a new token, trying again in %s: %v", delay, err)
+			if !sleep(ctx, delay) {
+				return
+			}
+			delay = min(2*delay, idle)
+			continue
+		}
+		delay = r.opts.RetryDelay
+		deferred = false
+	}
+}
+
+// sleep waits for d, reporting false if ctx ends first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// due is when token needs replacing: a little before it expires when it
+// can be refreshed, and once it has expired when only reauthorizing will
+// do, so as not to trouble the user early.
+func (r *Refresher) due(token *transport.Token) time.Time {
+	if token.RefreshToken == "" {
+		return token.ExpiresAt
+	}
+	return r.refreshAt(token)
+}
+
+// expiry describes when token expires.
+func expiry(token *transport.Token) string {
+	if token.ExpiresAt.IsZero() {
+		return "never"
+	}
+	return "at " + token.ExpiresAt.Format(time.TimeOnly)
+}
+
+// refreshAt is when token should be refreshed.
+func (r *Refresher) refreshAt(token *transport.Token) time.Time {
+	margin := r.opts.Margin
+	if life := time.Duration(token.ExpiresIn) * time.Second; life > 0 {
+		margin = min(margin, life/2)
+	}
+	return token.ExpiresAt.Add(-margin)
+}
+
+// renew gets a new token. Once a caller has it, the others waiting need
+// not: with a zero since, renew does nothing while the stored token is not
+// yet due for refresh, as when another process refreshed it; otherwise it
+// does nothing if a renewal succeeded after since. A zero since also means
+// renew runs in the background, where it reauthorizes only until that
+// fails once.
+func (r *Refresher) renew(ctx context.Context, since time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !since.IsZero() && r.renewed.After(since) {
+		return nil
+	}
+	if l, ok := r.store.(refreshLocker); ok {
+		unlock, err := l.LockRefresh(ctx)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	token, err := r.store.GetToken(ctx)
+	if err != nil && !errors.Is(err, transport.ErrNoToken) {
+		return err
+	}
+	if since.IsZero() && err == nil && time.Now().Before(r.due(token)) {
+		return nil
+	}
+
+	if err == nil && token.RefreshToken != "" {
+		fresh, rerr := r.handler.RefreshToken(ctx, token.RefreshToken)
+		if rerr == nil {
+			r.succeeded()
+			r.opts.Logf("oauth: token refreshed, expires %s", expiry(fresh))
+			return nil
+		}
+		// Keep using the token while it lasts, unless the authorization
+		// server has said that the refresh token is no good.
+		var oauthErr transport.OAuthError
+		if !errors.As(rerr, &oauthErr) && !token.IsExpired() && since.IsZero() {
+			return rerr
+		}
+		// The refresh token may have been used up by a process that does
+		// not take the lock, and the new token saved.
+		if latest, err := r.store.GetToken(ctx); err == nil && latest.AccessToken != token.AccessToken {
+			r.succeeded()
+			return nil
+		}
+		r.opts.Logf("oauth: cannot refresh the token: %v", rerr)
+	}
+
+	if r.opts.Reauthorize == nil {
+		return errors.New("the token cannot be refreshed and there is no way to reauthorize")
+	}
+	if since.IsZero() && r.reauthFailed {
+		return errReauthorizeLater
+	}
+	if err := r.opts.Reauthorize(ctx, r.handler); err != nil {
+		if since.IsZero() {
+			r.reauthFailed = true
+		}
+		return fmt.Errorf("reauthorize: %w", err)
+	}
+	r.succeeded()
+	return nil
+}
+
+// succeeded records a renewal. r.mu must be held.
+func (r *Refresher) succeeded() {
+	r.renewed = time.Now()
+	r.reauthFailed = false
+}
@@ -0,0 +1,113 @@
+package tokenstore
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// keySize is the length of an AES-256 key.
+const keySize = 32
+
+// LoadKey returns the key that seals the token file. It is taken from the
+// environment variable env when that is set, and otherwise from keyFile, which
+// is created with a new random key the first time. Either way the key is
+// written as base64.
+func LoadKey(env, keyFile string) ([]byte, error) {
+	if raw, ok := os.LookupEnv(env); ok && raw != "" {
+		key, err := decodeKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("environment %s: %w", env, err)
+		}
+		return key, nil
+	}
+	if keyFile == "" {
+		return nil, fmt.Errorf("no token key: set %s or name a key file", env)
+	}
+
+	key, err := readKeyFile(keyFile)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return key, err
+	}
+	key, err = createKeyFile(keyFile)
+	if errors.Is(err, fs.ErrExist) {
+		// Another process made it first.
+		return readKeyFile(keyFile)
+	}
+	return key, err
+}
+
+func readKeyFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	// As with ssh keys, a key others can read protects nothing.
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		return nil, fmt.Errorf("key file %q: permissions %#o are too open, want 0600", path, info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("key file: %w", err)
+	}
+	key, err := decodeKey(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("key file %q: %w", path, err)
+	}
+	return key, nil
+}
+
+// createKeyFile writes a new key to a temporary file and links it into
+// place, so that the key file never exists without its key. It fails with
+// fs.ErrExist if another process created the key file first.
+func createKeyFile(path string) ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("key file: %w", err)
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("key file: %w", err)
+	}
+	// CreateTemp makes the file with mode 0600.
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("key file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(base64.StdEncoding.EncodeToString(key) + "\n")
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key file: %w", err)
+	}
+	// Unlike a rename, a link does not replace a key file made meanwhile.
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("key file: %w", err)
+	}
+	return key, nil
+}
+
+func decodeKey(raw string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("want a base64 key: %v", err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("want a %d-byte key, got %d bytes", keySize, len(key))
+	}
+	return key, nil
+}
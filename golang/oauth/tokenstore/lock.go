@@ -0,0 +1,22 @@
+package tokenstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// lockPoll is how often a busy lock is tried again.
+const lockPoll = 20 * time.Millisecond
+
+// wait sleeps before the next attempt at a busy lock.
+func wait(ctx context.Context) error {
+	timer := time.NewTimer(lockPoll)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("token file lock: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,43 @@
+//go:build !unix
+
+package tokenstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// staleLock is how old a lock file must be before it is taken to belong to
+// a process that died holding it.
+const staleLock = 30 * time.Second
+
+// lock creates a lock file beside path, waiting for other processes as long
+// as ctx allows. Without flock readers and writers alike lock exclusively.
+func lock(ctx context.Context, path string, exclusive bool) (unlock func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("token file lock: %w", err)
+	}
+	name := path + ".lock"
+	for {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(name) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("token file lock: %w", err)
+		}
+		if info, err := os.Stat(name); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(name)
+			continue
+		}
+		if err := wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Package tokenstore keeps OAuth tokens in an encrypted file so that a
+// client stays authorized from one run to the next.
+//
+// One file holds the tokens of every server the user has authorized
+// against. Each token is filed under the server URL and the client ID it was
+// issued to, next to the client registration itself, so a dynamically
+// registered client is reused rather than registered again. The file is
+// sealed with AES-256-GCM, written with mode 0600 and locked while it is
+// read or changed, so several processes may share it.
+package tokenstore
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+// aad binds the ciphertext to this file format.
+var aad = []byte("mcp-oauth tokenstore v1")
+
+// Client is a client registration at a server's authorization server.
+type Client struct {
+	ID     string `json:"client_id"`
+	Secret string `json:"client_secret,omitempty"`
+	// RedirectURI is the redirect URI the client was registered with.
+	RedirectURI string `json:"redirect_uri,omitempty"`
+}
+
+// contents is what the file holds once decrypted.
+type contents struct {
+	// Clients maps a server URL to the client registered for it.
+	Clients map[string]Client `json:"clients,omitempty"`
+	// Tokens maps tokenKey(server URL, client ID) to a token.
+	Tokens map[string]*transport.Token `json:"tokens,omitempty"`
+}
+
+func tokenKey(serverURL, clientID string) string {
+	return serverURL + " " + clientID
+}
+
+// File is an encrypted token file.
+type File struct {
+	path string
+	aead cipher.AEAD
+}
+
+// Open returns the token file at path, sealed with key, which must be 32
+// bytes long. The file and its directory are created when a token is first
+// saved.
+func Open(path string, key []byte) (*File, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("token key: want %d bytes, got %d", keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("token key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("token key: %w", err)
+	}
+	return &File{path: path, aead: aead}, nil
+}
+
+// Server returns the store for serverURL, set to the client last saved for
+// it, if any.
+func (f *File) Server(ctx context.Context, serverURL string) (*Store, error) {
+	var c contents
+	if err := f.view(ctx, &c); err != nil {
+		return nil, err
+	}
+	return &Store{file: f, server: serverURL, client: c.Clients[serverURL]}, nil
+}
+
+// view reads the file into c under a shared lock. A missing file reads as
+// empty.
+func (f *File) view(ctx context.Context, c *contents) error {
+	unlock, err := lock(ctx, f.path, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return f.read(c)
+}
+
+// update applies fn to the file's contents under an exclusive lock and
+// writes the result back.
+func (f *File) update(ctx context.Context, fn func(*contents)) error {
+	unlock, err := lock(ctx, f.path, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	var c contents
+	if err := f.read(&c); err != nil {
+		return err
+	}
+	fn(&c)
+	return f.write(&c)
+}
+
+func (f *File) read(c *contents) error {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("token file: %w", err)
+	}
+	n := f.aead.NonceSize()
+	if len(data) < n {
+		return fmt.Errorf("token file %s: too short", f.path)
+	}
+	plain, err := f.aead.Open(nil, data[:n], data[n:], aad)
+	if err != nil {
+		return fmt.Errorf("token file %s: cannot decrypt, wrong key or damaged file", f.path)
+	}
+	if err := json.Unmarshal(plain, c); err != nil {
+		return fmt.Errorf("token file %s: %w", f.path, err)
+	}
+	return nil
+}
+
+// write replaces the file in one step, so a reader that does not take the
+// lock still sees either the old contents or the new.
+func (f *File) write(c *contents) error {
+	plain, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("token file: %w", err)
+	}
+	nonce := make([]byte, f.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("token file: %w", err)
+	}
+	data := f.aead.Seal(nonce, nonce, plain, aad)
+
+	dir := filepath.Dir(f.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("token file: %w", err)
+	}
+	// CreateTemp makes the file with mode 0600.
+	tmp, err := os.CreateTemp(dir, filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("token file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("token file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("token file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("token file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("token file: %w", err)
+	}
+	return nil
+}
+
+// Store is a transport.TokenStore for one server. It reads and saves the
+// token of the server's current client, so it follows a new registration
+// made with SetClient.
+type Store struct {
+	file   *File
+	server string
+
+	mu     sync.Mutex
+	client Client
+}
+
+// Client returns the client registration the store is using. Its ID is
+// empty when no client has been registered for the server yet.
+func (s *Store) Client() Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client
+}
+
+// SetClient saves a new client registration for the server and switches the
+// store to its token.
+func (s *Store) SetClient(ctx context.Context, c Client) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.file.update(ctx, func(contents *contents) {
+		if contents.Clients == nil {
+			contents.Clients = make(map[string]Client)
+		}
+		contents.Clients[s.server] = c
+	})
+	if err != nil {
+		return err
+	}
+	s.client = c
+	return nil
+}
+
+// GetToken returns the token saved for the server and current client, or
+// transport.ErrNoToken.
+func (s *Store) GetToken(ctx context.Context) (*transport.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	key := tokenKey(s.server, s.client.ID)
+	s.mu.Unlock()
+
+	var c contents
+	if err := s.file.view(ctx, &c); err != nil {
+		return nil, err
+	}
+	token, ok := c.Tokens[key]
+	if !ok || token == nil {
+		return nil, transport.ErrNoToken
+	}
+	return token, nil
+}
+
+// LockRefresh takes a lock, shared by every process using the file, on
+// renewing the server's token, waiting as long as ctx allows. A refresh token
+// may only be good once, so the holder should read the token again and only
+// renew it if it is still due. Call unlock when done.
+func (s *Store) LockRefresh(ctx context.Context) (unlock func(), err error) {
+	sum := sha256.Sum256([]byte(s.server))
+	return lock(ctx, s.file.path+".refresh-"+hex.EncodeToString(sum[:8]), true)
+}
+
+// SaveToken saves token for the server and current client.
+func (s *Store) SaveToken(ctx context.Context, token *transport.Token) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	key := tokenKey(s.server, s.client.ID)
+	s.mu.Unlock()
+
+	return s.file.update(ctx, func(c *contents) {
+		if c.Tokens == nil {
+			c.Tokens = make(map[string]*transport.Token)
+		}
+		c.Tokens[key] = token
+	})
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/duaraghav8/gomcptest-server/pubsub"
+	"github.com/duaraghav8/gomcptest-server/registry"
+	"github.com/duaraghav8/gomcptest-server/store"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// actionRunner performs the actions of scheduled jobs and webhooks. It
+// carries what they need from the rest of the server.
+type actionRunner struct {
+	srv      *server.MCPServer
+	store    store.SessionStore
+	sessions *registry.Registry
+	broker   *pubsub.Broker
+}
+
+// notifyAction sends a notification to every client, or publishes params to
+// a topic when one is given.
+type notifyAction struct {
+	Method string         `json:"method"`
+	Topic  string         `json:"topic"`
+	Params map[string]any `json:"params"`
+}
+
+// refreshAction reads a resource again and tells the sessions subscribed to
+// it that it was updated.
+type refreshAction struct {
+	URI string `json:"uri"`
+}
+
+// toolAction calls a registered tool, outside of any session, and publishes
+// its result to a topic if one is given.
+type toolAction struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+	Topic     string         `json:"topic"`
+}
+
+func (ar *actionRunner) notify(a *notifyAction) string {
+	if a.Topic != "" {
+		n := ar.broker.Publish(a.Topic, a.Params)
+		return fmt.Sprintf("published to %s, %d subscriber(s)", a.Topic, n)
+	}
+	n := ar.sessions.Count()
+	ar.srv.SendNotificationToAllClients(a.Method, a.Params)
+	return fmt.Sprintf("%s sent to %d session(s)", a.Method, n)
+}
+
+// refresh reads the resource through the server, so its handler produces
+// the current content and a broken resource fails the job, then tells its
+// subscribers it was updated.
+func (ar *actionRunner) refresh(ctx context.Context, a *refreshAction) (string, error) {
+	if _, err := ar.call(ctx, mcp.MethodResourcesRead, map[string]any{"uri": a.URI}); err != nil {
+		return "", err
+	}
+	n, err := ar.resourceUpdated(ctx, a.URI)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s refreshed, %d subscriber(s) notified", a.URI, n), nil
+}
+
+// resourceUpdated sends resources/updated to the sessions on this replica
+// subscribed to uri and returns how many there were.
+func (ar *actionRunner) resourceUpdated(ctx context.Context, uri string) (int, error) {
+	stored, err := ar.store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, sess := range stored {
+		if !slices.Contains(sess.Subscriptions, uri) {
+			continue
+		}
+		if _, ok := ar.sessions.Get(sess.ID); !ok {
+			continue
+		}
+		params := map[string]any{"uri": uri}
+		if err := ar.srv.SendNotificationToSpecificClient(sess.ID, mcp.MethodNotificationResourceUpdated, params); err == nil {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func (ar *actionRunner) callTool(ctx context.Context, a *toolAction) (string, error) {
+	raw, err := ar.call(ctx, mcp.MethodToolsCall, map[string]any{"name": a.Name, "arguments": a.Arguments})
+	if err != nil {
+		return "", err
+	}
+	res, err := mcp.ParseCallToolResult(&raw)
+	if err != nil {
+		return "", fmt.Errorf("decode %s result: %w", a.Name, err)
+	}
+	text := toolResultText(res)
+	if res.IsError {
+		return "", fmt.Errorf("%s: %s", a.Name, text)
+	}
+	if a.Topic != "" {
+		data := any(text)
+		if res.StructuredContent != nil {
+			data = res.StructuredContent
+		}
+		n := ar.broker.Publish(a.Topic, data)
+		return fmt.Sprintf("%s returned %q, published to %d subscriber(s)", a.Name, text, n), nil
+	}
+	return fmt.Sprintf("%s returned %q", a.Name, text), nil
+}
+
+// call sends a request straight to the server, as if from a client without a
+// session, and returns the raw result.
+func (ar *actionRunner) call(ctx context.Context, method mcp.MCPMethod, params any) (json.RawMessage, error) {
+	msg, err := json.Marshal(map[string]any{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.Marshal(ar.srv.HandleMessage(ctx, msg))
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%s: %s", method, resp.Error.Message)
+	}
+	return resp.Result, nil
+}
+
+func toolResultText(res *mcp.CallToolResult) string {
+	var parts []string
+	for _, c := range res.Content {
+		if tc, ok := c.(mcp.TextContent); ok {
+			parts = append(parts, tc.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
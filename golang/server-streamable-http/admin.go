@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/duaraghav8/gomcptest-server/pubsub"
+	"github.com/duaraghav8/gomcptest-server/registry"
+	"github.com/duaraghav8/gomcptest-server/scheduler"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// listChangedMethods maps the kinds accepted by POST /admin/list-changed to
+// their notification.
+var listChangedMethods = map[string]string{
+	"tools":     mcp.MethodNotificationToolsListChanged,
+	"resources": mcp.MethodNotificationResourcesListChanged,
+	"prompts":   mcp.MethodNotificationPromptsListChanged,
+}
+
+// adminAPI lets an operator inspect and disconnect sessions and push
+// notifications to them. Every request needs the bearer token.
+type adminAPI struct {
+	token      string
+	srv        *server.MCPServer
+	sessions   *registry.Registry
+	broker     *pubsub.Broker
+	jobs       *scheduler.Scheduler
+	delivery   *clientDelivery
+	disconnect func(ctx context.Context, sessionID string) error
+}
+
+// notifyRequest is the body of POST /admin/notify. The notification goes to
+// the given sessions, or to the given client identities, or else to every
+// connected client. Clients that are offline get it from the outbox when they
+// reconnect, if durable delivery is on.
+type notifyRequest struct {
+	Method   string         `json:"method"`
+	Params   map[string]any `json:"params,omitempty"`
+	Sessions []string       `json:"sessions,omitempty"`
+	Clients  []string       `json:"clients,omitempty"`
+}
+
+type listChangedRequest struct {
+	Kind     string   `json:"kind"`
+	Sessions []string `json:"sessions,omitempty"`
+}
+
+// publishRequest is the body of POST /admin/publish.
+type publishRequest struct {
+	Topic string `json:"topic"`
+	Data  any    `json:"data"`
+}
+
+// deliveryResult reports which sessions a notification was handed to. Sent
+// only means it was queued on the session, not that the client read it.
+type deliveryResult struct {
+	Sent   int               `json:"sent"`
+	Queued int               `json:"queued,omitempty"` // clients whose outbox holds it
+	Errors map[string]string `json:"errors,omitempty"` // session or client -> reason
+}
+
+// routes mounts the API on mux under prefix.
+func (a *adminAPI) routes(mux *http.ServeMux, prefix string) {
+	mux.Handle("GET "+prefix+"/sessions", a.auth(a.handleListSessions))
+	mux.Handle("GET "+prefix+"/sessions/{id}", a.auth(a.handleGetSession))
+	mux.Handle("DELETE "+prefix+"/sessions/{id}", a.auth(a.handleDisconnect))
+	mux.Handle("POST "+prefix+"/notify", a.auth(a.handleNotify))
+	mux.Handle("POST "+prefix+"/list-changed", a.auth(a.handleListChanged))
+	mux.Handle("POST "+prefix+"/publish", a.auth(a.handlePublish))
+	mux.Handle("GET "+prefix+"/jobs", a.auth(a.handleListJobs))
+	mux.Handle("GET "+prefix+"/jobs/{name}/runs", a.auth(a.handleJobRuns))
+	mux.Handle("POST "+prefix+"/jobs/{name}/run", a.auth(a.handleRunJob))
+	mux.Handle("GET "+prefix+"/outbox", a.auth(a.handleOutbox))
+}
+
+func (a *adminAPI) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (a *adminAPI) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{"sessions": a.sessions.List()})
+}
+
+func (a *adminAPI) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	info, ok := a.sessions.Get(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, info)
+}
+
+func (a *adminAPI) handleDisconnect(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	info, ok := a.sessions.Get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	if info.Transport == registry.TransportStdio {
+		http.Error(w, "stdio session cannot be disconnected", http.StatusConflict)
+		return
+	}
+	if err := a.disconnect(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[admin] disconnected %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *adminAPI) handleNotify(w http.ResponseWriter, r *http.Request) {
+	var req notifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Method == "" {
+		http.Error(w, "method is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Sessions) > 0 && len(req.Clients) > 0 {
+		http.Error(w, "give sessions or clients, not both", http.StatusBadRequest)
+		return
+	}
+	if len(req.Clients) > 0 {
+		writeJSON(w, a.sendToClients(req.Method, req.Params, req.Clients))
+		return
+	}
+	writeJSON(w, a.send(req.Method, req.Params, req.Sessions))
+}
+
+func (a *adminAPI) handleListChanged(w http.ResponseWriter, r *http.Request) {
+	var req listChangedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	method, ok := listChangedMethods[req.Kind]
+	if !ok {
+		http.Error(w, "kind must be tools, resources or prompts", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, a.send(method, nil, req.Sessions))
+}
+
+func (a *adminAPI) handlePublish(w http.ResponseWriter, r *http.Request) {
+	var req publishRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Topic == "" {
+		http.Error(w, "topic is required", http.StatusBadRequest)
+		return
+	}
+	n := a.broker.Publish(req.Topic, req.Data)
+	log.Printf("[admin] published to %s, %d subscriber(s)", req.Topic, n)
+	writeJSON(w, deliveryResult{Sent: n})
+}
+
+func (a *adminAPI) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{"jobs": a.jobs.Jobs()})
+}
+
+func (a *adminAPI) handleJobRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := a.jobs.History(r.PathValue("name"))
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, map[string]any{"runs": runs})
+}
+
+// handleRunJob starts a job now. The run is recorded in the job's history
+// like a scheduled one.
+func (a *adminAPI) handleRunJob(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	err := a.jobs.Trigger(name)
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	log.Printf("[admin] triggered job %s", name)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (a *adminAPI) handleOutbox(w http.ResponseWriter, r *http.Request) {
+	if a.delivery.box == nil {
+		http.Error(w, "durable delivery is off", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, map[string]any{"queued": a.delivery.box.Depths()})
+}
+
+// sendToClients delivers a notification to client identities, queueing it
+// for those that are offline.
+func (a *adminAPI) sendToClients(method string, params map[string]any, clients []string) deliveryResult {
+	res := deliveryResult{Errors: make(map[string]string)}
+	for _, c := range clients {
+		sent, queued, err := a.delivery.deliver(c, method, params)
+		switch {
+		case err != nil:
+			res.Errors[c] = err.Error()
+		case queued:
+			res.Queued++
+		}
+		res.Sent += sent
+	}
+	log.Printf("[admin] %s sent to %d session(s), queued for %d of %d client(s)", method, res.Sent, res.Queued, len(clients))
+	return res
+}
+
+// send delivers a notification to the given sessions, or to everyone when
+// there are none.
+func (a *adminAPI) send(method string, params map[string]any, sessionIDs []string) deliveryResult {
+	if len(sessionIDs) == 0 {
+		n := a.sessions.Count()
+		a.srv.SendNotificationToAllClients(method, params)
+		log.Printf("[admin] %s sent to all %d session(s)", method, n)
+		return deliveryResult{Sent: n}
+	}
+
+	res := deliveryResult{Errors: make(map[string]string)}
+	for _, id := range sessionIDs {
+		if err := a.srv.SendNotificationToSpecificClient(id, method, params); err != nil {
+			res.Errors[id] = err.Error()
+			continue
+		}
+		res.Sent++
+	}
+	log.Printf("[admin] %s sent to %d of %d session(s)", method, res.Sent, len(sessionIDs))
+	return res
+}
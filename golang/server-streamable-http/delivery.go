@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/duaraghav8/gomcptest-server/outbox"
+	"github.com/duaraghav8/gomcptest-server/registry"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+var errClientOffline = errors.New("client is not connected")
+
+// clientDelivery sends notifications to clients by identity rather than by
+// session. With an outbox, what cannot be delivered right away is queued and
+// sent, in order, when a session with that identity connects.
+type clientDelivery struct {
+	srv      *server.MCPServer
+	sessions *registry.Registry
+	box      *outbox.Outbox // nil when durable delivery is off
+}
+
+// register flushes a client's outbox on each request it makes after
+// initialize, on the SSE and stdio transports. Streamable HTTP sessions are
+// flushed by flushOnStream instead: mcp-go can drop a notification sent while
+// one of their POST requests is being handled.
+func (d *clientDelivery) register(hooks *server.Hooks) {
+	if d.box == nil {
+		return
+	}
+	hooks.AddBeforeAny(func(ctx context.Context, id any, method mcp.MCPMethod, message any) {
+		sess := server.ClientSessionFromContext(ctx)
+		if sess == nil || method == mcp.MethodInitialize {
+			return
+		}
+		if info, ok := d.sessions.Get(sess.SessionID()); ok && info.Transport != registry.TransportStreamable {
+			d.flush(info.Identity())
+		}
+	})
+}
+
+// flushOnStream flushes a client's outbox when it opens a streamable HTTP GET
+// stream, which is the only place its queued messages are sent. Clients that
+// never open one keep them queued until they expire.
+func (d *clientDelivery) flushOnStream(next http.Handler) http.Handler {
+	if d.box == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := r.Header.Get(server.HeaderKeySessionID); r.Method == http.MethodGet && id != "" {
+			d.flushSession(id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (d *clientDelivery) flushSession(sessionID string) {
+	if info, ok := d.sessions.Get(sessionID); ok {
+		d.flush(info.Identity())
+	}
+}
+
+// deliver sends a notification to every session of identity. It reports how
+// many sessions took it and whether it was queued instead.
+func (d *clientDelivery) deliver(identity, method string, params map[string]any) (sent int, queued bool, err error) {
+	if d.box == nil {
+		sent = d.send(identity, method, params)
+		if sent == 0 {
+			return 0, false, errClientOffline
+		}
+		return sent, false, nil
+	}
+
+	// Anything already waiting has to go first.
+	if d.box.Len(identity) == 0 {
+		if sent = d.send(identity, method, params); sent > 0 {
+			return sent, false, nil
+		}
+	}
+	dropped, err := d.box.Push(identity, method, params)
+	if err != nil {
+		return 0, false, err
+	}
+	if dropped > 0 {
+		log.Printf("[outbox] %s: queue full, dropped %d oldest message(s)", identity, dropped)
+	}
+	d.flush(identity)
+	return 0, true, nil
+}
+
+// flush delivers identity's queued messages while one of its sessions is
+// connected.
+func (d *clientDelivery) flush(identity string) {
+	if identity == "" {
+		return
+	}
+	n, err := d.box.Flush(identity, func(m outbox.Message) error {
+		if d.send(identity, m.Method, m.Params) == 0 {
+			return errClientOffline
+		}
+		return nil
+	})
+	if n > 0 {
+		log.Printf("[outbox] %s: delivered %d queued message(s)", identity, n)
+	}
+	if err != nil && !errors.Is(err, errClientOffline) {
+		log.Printf("[outbox] %s: %v", identity, err)
+	}
+}
+
+// send hands the notification to every connected session of identity and
+// returns how many accepted it.
+func (d *clientDelivery) send(identity, method string, params map[string]any) int {
+	sent := 0
+	for _, id := range d.sessions.SessionsOf(identity) {
+		if err := d.srv.SendNotificationToSpecificClient(id, method, params); err != nil {
+			log.Printf("[outbox] %s: send %s to %s: %v", identity, method, id, err)
+			continue
+		}
+		sent++
+	}
+	return sent
+}
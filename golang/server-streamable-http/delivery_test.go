@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duaraghav8/gomcptest-server/outbox"
+	"github.com/duaraghav8/gomcptest-server/registry"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// TestDeliverQueuesForClientWithoutStream checks that a streamable HTTP
+// client without an open GET stream gets its notifications from the outbox,
+// in order, once it opens one.
+func TestDeliverQueuesForClientWithoutStream(t *testing.T) {
+	hooks := &server.Hooks{}
+	srv := server.NewMCPServer("test", "1.0.0", server.WithHooks(hooks))
+	sessions := registry.New(srv, registry.Options{})
+	sessions.Register(hooks)
+	box, err := outbox.New(t.TempDir(), 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivery := &clientDelivery{srv: srv, sessions: sessions, box: box}
+	delivery.register(hooks)
+
+	streamable := server.NewStreamableHTTPServer(srv)
+	ts := httptest.NewServer(sessions.Track(registry.TransportStreamable, delivery.flushOnStream(streamable)))
+	defer ts.Close()
+
+	sessionID := post(t, ts.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"alice","version":"1"}}}`)
+	post(t, ts.URL, sessionID, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	for _, n := range []int{1, 2} {
+		sent, queued, err := delivery.deliver("alice", "notifications/test", map[string]any{"n": n})
+		if err != nil || sent != 0 || !queued {
+			t.Fatalf("deliver %d while offline = %d, %v, %v; want it queued", n, sent, queued, err)
+		}
+	}
+	if got := box.Len("alice"); got != 2 {
+		t.Fatalf("outbox holds %d messages, want 2", got)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(server.HeaderKeySessionID, sessionID)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := make(chan float64)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var msg struct {
+				Params struct {
+					N float64 `json:"n"`
+				} `json:"params"`
+			}
+			if json.Unmarshal([]byte(data), &msg) == nil {
+				events <- msg.Params.N
+			}
+		}
+	}()
+
+	next := func() float64 {
+		t.Helper()
+		select {
+		case n := <-events:
+			return n
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification on the stream")
+			return 0
+		}
+	}
+	for _, want := range []float64{1, 2} {
+		if got := next(); got != want {
+			t.Fatalf("got notification %v, want %v", got, want)
+		}
+	}
+	if got := box.Len("alice"); got != 0 {
+		t.Errorf("outbox holds %d messages after the flush, want 0", got)
+	}
+
+	sent, queued, err := delivery.deliver("alice", "notifications/test", map[string]any{"n": 3})
+	if err != nil || sent != 1 || queued {
+		t.Fatalf("deliver while connected = %d, %v, %v; want it sent", sent, queued, err)
+	}
+	if got := next(); got != 3 {
+		t.Fatalf("got notification %v, want 3", got)
+	}
+}
+
+// post sends a JSON-RPC message and returns the session ID the server
+// answers with.
+func post(t *testing.T, url, sessionID, body string) string {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if sessionID != "" {
+		req.Header.Set(server.HeaderKeySessionID, sessionID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		t.Fatalf("POST %s: %s", body, resp.Status)
+	}
+	return resp.Header.Get(server.HeaderKeySessionID)
+}
@@ -2,12 +2,15 @@ module github.com/duaraghav8/gomcptest-server
 
 go 1.24.3
 
-require github.com/mark3labs/mcp-go v0.38.0
+require (
+	github.com/duaraghav8/mcp-config v0.0.0
+	github.com/google/uuid v1.6.0
+	github.com/mark3labs/mcp-go v0.43.0
+)
 
 require (
 	github.com/bahlo/generic-list-go v0.2.0 // indirect
 	github.com/buger/jsonparser v1.1.1 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/invopop/jsonschema v0.13.0 // indirect
 	github.com/mailru/easyjson v0.9.0 // indirect
 	github.com/spf13/cast v1.9.2 // indirect
@@ -15,3 +18,5 @@ require (
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/duaraghav8/mcp-config => ../config
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/duaraghav8/gomcptest-server/registry"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// healthCheckTimeout bounds a single readiness check, so one slow dependency
+// cannot hold the probe past the load balancer's own timeout.
+const healthCheckTimeout = 2 * time.Second
+
+type healthCheck struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+type checkResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type healthReport struct {
+	Status string        `json:"status"`
+	Checks []checkResult `json:"checks"`
+}
+
+// health serves /healthz and /readyz. Liveness only says the process is
+// serving HTTP; readiness runs every check and fails if any of them does.
+type health struct {
+	checks []healthCheck
+}
+
+func (h *health) add(name string, run func(ctx context.Context) error) {
+	h.checks = append(h.checks, healthCheck{name: name, run: run})
+}
+
+func (h *health) handleLive(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, healthReport{Status: "ok", Checks: []checkResult{}})
+}
+
+func (h *health) handleReady(w http.ResponseWriter, r *http.Request) {
+	results := make([]checkResult, len(h.checks))
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			err := c.run(ctx)
+			results[i] = checkResult{
+				Name:     c.name,
+				Status:   "ok",
+				Duration: time.Since(start).Round(time.Microsecond).String(),
+			}
+			if err != nil {
+				results[i].Status = "fail"
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := healthReport{Status: "ok", Checks: results}
+	for _, res := range results {
+		if res.Status != "ok" {
+			report.Status = "fail"
+		}
+	}
+	if report.Status != "ok" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	writeJSON(w, report)
+}
+
+// checkDraining fails once shutdown has started.
+func checkDraining(gate *drainGate) func(context.Context) error {
+	return func(context.Context) error {
+		if gate.draining.Load() {
+			return errors.New("server is shutting down")
+		}
+		return nil
+	}
+}
+
+// checkSessionCap fails while the registry is full.
+func checkSessionCap(reg *registry.Registry) func(context.Context) error {
+	return func(context.Context) error {
+		if reg.Full() {
+			return fmt.Errorf("%d of %d sessions in use", reg.Count(), reg.MaxSessions())
+		}
+		return nil
+	}
+}
+
+// checkDir fails if dir is missing, not a directory or cannot be listed.
+func checkDir(dir string) func(context.Context) error {
+	return func(context.Context) error {
+		f, err := os.Open(dir)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+		if _, err := f.Readdirnames(1); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	}
+}
+
+// upstreamCheck pings an upstream MCP server over streamable HTTP. The session
+// is kept between probes and only re-established after a failure, so probing
+// does not open a new upstream session every few seconds.
+type upstreamCheck struct {
+	url string
+
+	mu  sync.Mutex
+	cli *client.Client
+}
+
+func (u *upstreamCheck) run(ctx context.Context) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.cli == nil {
+		cli, err := u.connect(ctx)
+		if err != nil {
+			return err
+		}
+		u.cli = cli
+	}
+	if err := u.cli.Ping(ctx); err != nil {
+		u.cli.Close()
+		u.cli = nil
+		return fmt.Errorf("ping: %w", err)
+	}
+	return nil
+}
+
+func (u *upstreamCheck) connect(ctx context.Context) (*client.Client, error) {
+	cli, err := client.NewStreamableHttpClient(u.url)
+	if err != nil {
+		return nil, err
+	}
+	if err := cli.Start(ctx); err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	req := mcp.InitializeRequest{}
+	req.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	req.Params.ClientInfo = mcp.Implementation{Name: "readiness-probe", Version: "1.0.0"}
+	if _, err := cli.Initialize(ctx, req); err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	return cli, nil
+}
+
+// close ends the upstream session, if one is open.
+func (u *upstreamCheck) close() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.cli != nil {
+		u.cli.Close()
+		u.cli = nil
+	}
+}
+
+// splitList splits a comma-separated setting, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxRPCBody bounds the size of a message posted to the streamable HTTP
+// endpoint, which rpcInterceptor reads into memory whole.
+const maxRPCBody = 4 << 20
+
+// rpcRequest is a JSON-RPC request as far as rpcInterceptor decodes it: the
+// parameters are left for the method's handler.
+type rpcRequest struct {
+	ID     mcp.RequestId   `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// rpcMethod answers one intercepted request.
+type rpcMethod func(w http.ResponseWriter, r *http.Request, req rpcRequest)
+
+// rpcInterceptor answers the requests that mcp-go has no way to register a
+// handler for, on the streamable HTTP endpoint. It reads each POST body once
+// and dispatches by method; every other message is passed on unchanged.
+type rpcInterceptor struct {
+	next    http.Handler
+	methods map[string]rpcMethod
+}
+
+func (h *rpcInterceptor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRPCBody))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "read request body", http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(raw))
+
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	method, ok := h.methods[req.Method]
+	if !ok {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	method(w, r, req)
+}
+
+// params decodes the request's parameters into v, answering with an invalid
+// params error if they do not fit.
+func (req rpcRequest) params(w http.ResponseWriter, v any) bool {
+	if len(req.Params) == 0 {
+		return true
+	}
+	if err := json.Unmarshal(req.Params, v); err != nil {
+		writeJSON(w, mcp.NewJSONRPCError(req.ID, mcp.INVALID_PARAMS, err.Error(), nil))
+		return false
+	}
+	return true
+}
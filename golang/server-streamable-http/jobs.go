@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/duaraghav8/gomcptest-server/scheduler"
+)
+
+// jobsFile is the format of the file named by -jobs-file:
+//
+//	{"jobs": [
+//	  {"name": "heartbeat", "schedule": "*/5 * * * *", "jitter": "30s",
+//	   "notify": {"topic": "status", "params": {"ok": true}}},
+//	  {"name": "sample", "schedule": "@hourly",
+//	   "refresh": {"uri": "file://sample.txt"}},
+//	  {"name": "sum", "schedule": "@every 1m", "overlap": "queue", "timeout": "10s",
+//	   "tool": {"name": "add", "arguments": {"a": 1, "b": 2}, "topic": "sums"}}
+//	]}
+//
+// Each job has exactly one of notify, refresh or tool.
+type jobsFile struct {
+	Jobs []jobConfig `json:"jobs"`
+}
+
+type jobConfig struct {
+	Name     string         `json:"name"`
+	Schedule string         `json:"schedule"`
+	Jitter   duration       `json:"jitter"`
+	Overlap  string         `json:"overlap"`
+	Timeout  duration       `json:"timeout"`
+	Notify   *notifyAction  `json:"notify"`
+	Refresh  *refreshAction `json:"refresh"`
+	Tool     *toolAction    `json:"tool"`
+}
+
+// duration is a time.Duration written as a string such as "30s" in JSON.
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+// loadJobs reads the jobs file at path and adds its jobs to sched.
+func (ar *actionRunner) loadJobs(path string, sched *scheduler.Scheduler) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var f jobsFile
+	dec := json.NewDecoder(file)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	var errs []error
+	for i, jc := range f.Jobs {
+		job, err := ar.job(jc)
+		if err == nil {
+			err = sched.Add(job)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: job %d (%s): %w", path, i+1, jc.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (ar *actionRunner) job(jc jobConfig) (scheduler.Job, error) {
+	sched, err := scheduler.Parse(jc.Schedule)
+	if err != nil {
+		return scheduler.Job{}, err
+	}
+	overlap, err := scheduler.ParseOverlap(jc.Overlap)
+	if err != nil {
+		return scheduler.Job{}, err
+	}
+	job := scheduler.Job{
+		Name:     jc.Name,
+		Spec:     jc.Schedule,
+		Schedule: sched,
+		Jitter:   time.Duration(jc.Jitter),
+		Overlap:  overlap,
+		Timeout:  time.Duration(jc.Timeout),
+	}
+
+	actions := 0
+	if a := jc.Notify; a != nil {
+		actions++
+		if a.Method == "" && a.Topic == "" {
+			return job, errors.New("notify needs a method or a topic")
+		}
+		job.Run = func(ctx context.Context) (string, error) { return ar.notify(a), nil }
+	}
+	if a := jc.Refresh; a != nil {
+		actions++
+		if a.URI == "" {
+			return job, errors.New("refresh needs a uri")
+		}
+		job.Run = func(ctx context.Context) (string, error) { return ar.refresh(ctx, a) }
+	}
+	if a := jc.Tool; a != nil {
+		actions++
+		if a.Name == "" {
+			return job, errors.New("tool needs a name")
+		}
+		job.Run = func(ctx context.Context) (string, error) { return ar.callTool(ctx, a) }
+	}
+	if actions != 1 {
+		return job, errors.New("needs exactly one of notify, refresh or tool")
+	}
+	return job, nil
+}
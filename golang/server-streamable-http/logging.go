@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// sessionLogger writes records to the local log and forwards them to the
+// client session found in the handler's context as notifications/message.
+//
+// The minimum level is tracked per session by mcp-go: every session starts at
+// "error" and the client changes it with logging/setLevel. Records below that
+// level are not sent to the client, but are still written locally.
+type sessionLogger struct {
+	ctx  context.Context
+	srv  *server.MCPServer
+	name string
+}
+
+// loggerFromContext returns a logger bound to the session of the request
+// being handled. name is reported to the client as the logger name.
+func loggerFromContext(ctx context.Context, name string) *sessionLogger {
+	return &sessionLogger{
+		ctx:  ctx,
+		srv:  server.ServerFromContext(ctx),
+		name: name,
+	}
+}
+
+func (l *sessionLogger) Debugf(format string, args ...any) {
+	l.log(mcp.LoggingLevelDebug, format, args...)
+}
+
+func (l *sessionLogger) Infof(format string, args ...any) {
+	l.log(mcp.LoggingLevelInfo, format, args...)
+}
+
+func (l *sessionLogger) Noticef(format string, args ...any) {
+	l.log(mcp.LoggingLevelNotice, format, args...)
+}
+
+func (l *sessionLogger) Warningf(format string, args ...any) {
+	l.log(mcp.LoggingLevelWarning, format, args...)
+}
+
+func (l *sessionLogger) Errorf(format string, args ...any) {
+	l.log(mcp.LoggingLevelError, format, args...)
+}
+
+func (l *sessionLogger) log(level mcp.LoggingLevel, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+
+	var sid string
+	if sess := server.ClientSessionFromContext(l.ctx); sess != nil {
+		sid = sess.SessionID()
+	}
+	log.Printf("[%s] %s %s: %s", sid, level, l.name, msg)
+
+	if l.srv == nil || sid == "" {
+		return
+	}
+	err := l.srv.SendLogMessageToClient(l.ctx, mcp.NewLoggingMessageNotification(level, l.name, msg))
+	if err != nil {
+		log.Printf("[logging] forwarding to %s failed: %v", sid, err)
+	}
+}
+
+// logLevelChanges records every logging/setLevel in the local log.
+func logLevelChanges(hooks *server.Hooks) {
+	hooks.AddAfterSetLevel(func(ctx context.Context, id any, req *mcp.SetLevelRequest, _ *mcp.EmptyResult) {
+		if sess := server.ClientSessionFromContext(ctx); sess != nil {
+			log.Printf("[logging] %s level set to %s", sess.SessionID(), req.Params.Level)
+		}
+	})
+}
@@ -1,242 +1,329 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
-	"encoding/binary"
-	"fmt"
-	"math/rand"
+	"errors"
+	"expvar"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/duaraghav8/gomcptest-server/outbox"
+	"github.com/duaraghav8/gomcptest-server/pubsub"
+	"github.com/duaraghav8/gomcptest-server/registry"
+	"github.com/duaraghav8/gomcptest-server/scheduler"
+	"github.com/duaraghav8/gomcptest-server/state"
+	"github.com/duaraghav8/gomcptest-server/store"
+	"github.com/duaraghav8/mcp-config"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
-type CustomMCPServer struct {
-	*server.MCPServer
+// options are the server's settings: the shared ones from the config package
+// plus those specific to this server.
+type options struct {
+	config.Server
+	DrainTimeout    time.Duration `flag:"drain-timeout" env:"MCP_DRAIN_TIMEOUT" json:"drain_timeout" usage:"how long in-flight requests may run after a shutdown signal"`
+	ReplayBuffer    int           `flag:"replay-buffer" env:"MCP_REPLAY_BUFFER" json:"replay_buffer" usage:"number of stream events kept per session for Last-Event-ID replay"`
+	SessionDir      string        `flag:"session-dir" env:"MCP_SESSION_DIR" json:"session_dir" usage:"directory to persist sessions in, shared by all replicas (default: in memory)"`
+	MaxSessions     int           `flag:"max-sessions" env:"MCP_MAX_SESSIONS" json:"max_sessions" usage:"maximum number of sessions, new ones are refused and /readyz fails at the cap, 0 for no limit"`
+	IdleTTL         time.Duration `flag:"session-idle-ttl" env:"MCP_SESSION_IDLE_TTL" json:"session_idle_ttl" usage:"disconnect sessions that sent no request for this long, 0 keeps them but drops their stored record after 24h"`
+	StateMaxKeys    int           `flag:"state-max-keys" env:"MCP_STATE_MAX_KEYS" json:"state_max_keys" usage:"keys a session may keep in its state, 0 for no limit"`
+	StateMaxBytes   int           `flag:"state-max-bytes" env:"MCP_STATE_MAX_BYTES" json:"state_max_bytes" usage:"total size of a session's state in bytes, 0 for no limit"`
+	StateTTL        time.Duration `flag:"state-ttl" env:"MCP_STATE_TTL" json:"state_ttl" usage:"how long session state values live when set without a TTL, 0 keeps them"`
+	PrincipalHeader string        `flag:"principal-header" env:"MCP_PRINCIPAL_HEADER" json:"principal_header" usage:"request header with the user authenticated by a proxy, recorded per session"`
+	ReadyDirs       string        `flag:"ready-dirs" env:"MCP_READY_DIRS" json:"ready_dirs" usage:"comma-separated directories that must be readable for /readyz to pass"`
+	ReadyUpstreams  string        `flag:"ready-upstreams" env:"MCP_READY_UPSTREAMS" json:"ready_upstreams" usage:"comma-separated streamable HTTP MCP servers that must answer ping for /readyz to pass"`
+	AdminToken      string        `flag:"admin-token" env:"MCP_ADMIN_TOKEN" json:"admin_token" usage:"bearer token for the /admin API, which is disabled without one"`
+	TopicBuffer     int           `flag:"topic-buffer" env:"MCP_TOPIC_BUFFER" json:"topic_buffer" usage:"topic messages queued per session before the drop policy applies"`
+	TopicDrop       string        `flag:"topic-drop-policy" env:"MCP_TOPIC_DROP_POLICY" json:"topic_drop_policy" usage:"what to drop when a session's topic queue is full: drop-oldest or drop-newest"`
+	JobsFile        string        `flag:"jobs-file" env:"MCP_JOBS_FILE" json:"jobs_file" usage:"JSON file of scheduled jobs that send notifications, refresh resources or call tools"`
+	JobHistory      int           `flag:"job-history" env:"MCP_JOB_HISTORY" json:"job_history" usage:"runs kept per scheduled job for the admin API"`
+	OutboxDepth     int           `flag:"outbox-depth" env:"MCP_OUTBOX_DEPTH" json:"outbox_depth" usage:"notifications queued per offline client identity for delivery on reconnect, 0 turns durable delivery off"`
+	OutboxTTL       time.Duration `flag:"outbox-ttl" env:"MCP_OUTBOX_TTL" json:"outbox_ttl" usage:"how long a queued notification waits for its client before it expires, 0 keeps it"`
+	OutboxDir       string        `flag:"outbox-dir" env:"MCP_OUTBOX_DIR" json:"outbox_dir" usage:"directory to persist the outbox in, so it survives a restart (default: in memory)"`
+	WebhooksFile    string        `flag:"webhooks-file" env:"MCP_WEBHOOKS_FILE" json:"webhooks_file" usage:"JSON file of signed webhooks, served under /hooks, that become notifications, topic messages or resource updates"`
 }
 
-func (s *CustomMCPServer) AddTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
-	fmt.Printf("********** Adding tool: %s\n", tool.Name)
-	s.MCPServer.AddTool(tool, handler)
-}
+func main() {
+	// stdout carries the stdio transport, so all logging goes to stderr.
+	log.SetOutput(os.Stderr)
 
-type Person struct {
-	Name string `json:"name"`
-	Age  int    `json:"age"`
-}
+	opts := options{
+		Server:       config.DefaultServer(),
+		DrainTimeout: 15 * time.Second,
+		ReplayBuffer: 256,
+		TopicBuffer:  64,
+		TopicDrop:    string(pubsub.DropOldest),
+		JobHistory:   20,
+		OutboxTTL:    24 * time.Hour,
+	}
+	if err := config.Register(flag.CommandLine, &opts).Parse(os.Args[1:]); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := opts.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if opts.ReplayBuffer < 1 {
+		log.Fatalf("Invalid configuration:\nreplay-buffer %d: must be at least 1", opts.ReplayBuffer)
+	}
+	if opts.MaxSessions < 0 {
+		log.Fatalf("Invalid configuration:\nmax-sessions %d: must not be negative", opts.MaxSessions)
+	}
+	if opts.StateMaxKeys < 0 || opts.StateMaxBytes < 0 || opts.StateTTL < 0 {
+		log.Fatalf("Invalid configuration:\nstate-max-keys, state-max-bytes and state-ttl must not be negative")
+	}
+	if opts.TopicBuffer < 1 {
+		log.Fatalf("Invalid configuration:\ntopic-buffer %d: must be at least 1", opts.TopicBuffer)
+	}
+	dropPolicy, err := pubsub.ParseDropPolicy(opts.TopicDrop)
+	if err != nil {
+		log.Fatalf("Invalid configuration:\ntopic-drop-policy: %v", err)
+	}
+	if opts.JobHistory < 1 {
+		log.Fatalf("Invalid configuration:\njob-history %d: must be at least 1", opts.JobHistory)
+	}
+	if opts.OutboxDepth < 0 || opts.OutboxTTL < 0 {
+		log.Fatalf("Invalid configuration:\noutbox-depth and outbox-ttl must not be negative")
+	}
+	if opts.IdleTTL < 0 {
+		log.Fatalf("Invalid configuration:\nsession-idle-ttl %s: must not be negative", opts.IdleTTL)
+	}
+	for _, u := range splitList(opts.ReadyUpstreams) {
+		if err := config.CheckURL("ready-upstreams", u); err != nil {
+			log.Fatalf("Invalid configuration:\n%v", err)
+		}
+	}
 
-type InputSchema struct {
-	Sender   Person `json:"sender"`
-	Receiver Person `json:"receiver"`
-}
+	var sessionStore store.SessionStore = store.NewMemoryStore()
+	if opts.SessionDir != "" {
+		fileStore, err := store.NewFileStore(opts.SessionDir)
+		if err != nil {
+			log.Fatalf("Failed to open session store: %v", err)
+		}
+		sessionStore = fileStore
+	}
 
-type OutputSchema struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-}
+	roots := newSessionRoots()
+	m := newMetrics()
+	replay := newReplayBuffer(opts.ReplayBuffer)
+	hooks := &server.Hooks{}
+	roots.register(hooks)
+	replay.register(hooks)
+	m.register(hooks)
+	logLevelChanges(hooks)
+	persistSessions(sessionStore, hooks, roots, recordTTL(opts.IdleTTL))
+	states := state.NewManager(sessionStore, state.Limits{
+		MaxKeys:    opts.StateMaxKeys,
+		MaxBytes:   opts.StateMaxBytes,
+		DefaultTTL: opts.StateTTL,
+	})
+	states.Register(hooks)
 
-func main() {
 	mcpServer := server.NewMCPServer(
-		"math-tools",
-		"1.0.0",
+		opts.Name,
+		opts.Version,
+		server.WithInstructions(opts.Instructions),
 		server.WithToolCapabilities(true),
+		server.WithResourceCapabilities(true, false),
+		server.WithLogging(),
+		server.WithHooks(hooks),
 	)
-	customServer := &CustomMCPServer{mcpServer}
-
-	echoTool := mcp.NewTool(
-		"echo",
-		mcp.WithDescription("echoes back your message"),
-		mcp.WithString(
-			"message",
-			mcp.Description("Your message"),
-			mcp.Required(),
-		),
-	)
-	customServer.AddTool(echoTool, handleEchoToolCall)
-
-	audioTool := mcp.NewTool(
-		"return_audio",
-		mcp.WithDescription("returns random audio"),
-	)
-	customServer.AddTool(audioTool, handleAudioToolCall)
-
-	structuredContentTool := mcp.NewTool(
-		"structured_content",
-		mcp.WithDescription("returns structured content"),
-	)
-	customServer.AddTool(structuredContentTool, handleStructuredContentCall)
-
-	myResource := mcp.NewResource(
-		"file://sample.txt",
-		"sample_text_file",
-		mcp.WithResourceDescription("Sample text file resource"),
-		mcp.WithMIMEType("text/plain"),
-	)
-	customServer.AddResource(myResource, resourceHandler)
-
-	withSchemaTool := mcp.NewTool(
-		"tool_with_output_schema",
-		mcp.WithDescription("has schema for output"),
-		mcp.WithInputSchema[InputSchema](),
-		mcp.WithOutputSchema[OutputSchema](),
-	)
-	customServer.AddTool(withSchemaTool, handleWithSchemaCall)
-
-	egPrompt := mcp.NewPrompt(
-		"echo",
-		mcp.WithArgument(
-			"message",
-			mcp.RequiredArgument(),
-			mcp.ArgumentDescription("Message to echo"),
-		),
-	)
+	mcpServer.AddNotificationHandler(mcp.MethodNotificationRootsListChanged, roots.handleListChanged)
+	broker := pubsub.NewBroker(mcpServer, opts.TopicBuffer, dropPolicy)
+	broker.Register(hooks)
+	expvar.Publish("mcp_topic_messages_dropped", expvar.Func(func() any { return broker.Dropped() }))
+	registerTools(&CustomMCPServer{mcpServer}, roots, states, broker)
 
-	customServer.AddPrompt(egPrompt, egPromptHandler)
+	// The registry needs the server to disconnect sessions, so it is created
+	// last and adds its hooks to the ones the server already holds.
+	var sessions *registry.Registry
+	sessions = registry.New(mcpServer, registry.Options{
+		IdleTTL:         opts.IdleTTL,
+		MaxSessions:     opts.MaxSessions,
+		PrincipalHeader: opts.PrincipalHeader,
+		Evict: func(ctx context.Context, sessionID string) {
+			if err := terminateSession(ctx, sessionStore, sessions, sessionID); err != nil {
+				log.Printf("[sessions] evict %s: %v", sessionID, err)
+			}
+		},
+	})
+	sessions.Register(hooks)
 
-	httpServer := server.NewStreamableHTTPServer(customServer.MCPServer)
-	fmt.Printf("Listening on port :9000/mcp\n")
-	if err := httpServer.Start(":9000"); err != nil {
-		fmt.Printf("Failed to start server: %v\n", err)
-		return
+	// The outbox needs the identities the registry records, so its hooks go
+	// after the registry's.
+	delivery := &clientDelivery{srv: mcpServer, sessions: sessions}
+	if opts.OutboxDepth > 0 {
+		box, err := outbox.New(opts.OutboxDir, opts.OutboxDepth, opts.OutboxTTL)
+		if err != nil {
+			log.Fatalf("Failed to open outbox: %v", err)
+		}
+		delivery.box = box
 	}
-}
+	delivery.register(hooks)
 
-func handleEchoToolCall(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	fmt.Println(request.GetString("message", ""))
-	fmt.Println(request)
-	arguments := request.GetArguments()
-	message, ok := arguments["message"].(string)
-	fmt.Println(message, ok)
-	if !ok {
-		return nil, fmt.Errorf("invalid message argument")
-	}
-
-	embedded := mcp.EmbeddedResource{
-		Type: "resource",
-		Resource: mcp.TextResourceContents{
-			URI:      "resource://embedded-tool",
-			MIMEType: "text/plain",
-			Text:     "This is embedded resource content!",
-		},
-		Meta: nil,
+	actions := &actionRunner{srv: mcpServer, store: sessionStore, sessions: sessions, broker: broker}
+	jobs := scheduler.New(opts.JobHistory)
+	if opts.JobsFile != "" {
+		if err := actions.loadJobs(opts.JobsFile, jobs); err != nil {
+			log.Fatalf("Invalid configuration:\n%v", err)
+		}
 	}
 
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			mcp.TextContent{
-				Type: "text",
-				Text: fmt.Sprintf("Echo: %s", message),
+	// transports holds the MCP endpoints; they all go through the drain gate.
+	transports := http.NewServeMux()
+	gate := newDrainGate(transports)
+	mux := http.NewServeMux()
+	handle := func(path, transport string, h http.Handler) {
+		transports.Handle(opts.Endpoint(path), sessions.Track(transport, h))
+		mux.Handle(opts.Endpoint(path), gate)
+	}
+	if opts.Streamable {
+		streamable := server.NewStreamableHTTPServer(
+			mcpServer,
+			server.WithSessionIdManager(store.NewIDManager(sessionStore)),
+			server.WithHeartbeatInterval(opts.Heartbeat),
+		)
+		subscriptions := resourceSubscriptions(sessionStore)
+		topics := topicRequests(broker, sessions)
+		handle(opts.StreamablePath, registry.TransportStreamable, &replayHandler{
+			next: &rpcInterceptor{
+				next: delivery.flushOnStream(streamable),
+				methods: map[string]rpcMethod{
+					methodResourcesSubscribe:   subscriptions,
+					methodResourcesUnsubscribe: subscriptions,
+					methodTopicsSubscribe:      topics,
+					methodTopicsUnsubscribe:    topics,
+				},
 			},
-			mcp.NewResourceLink(
-				"file:///example/resource.txt",
-				"sample text",
-				"An example text resource",
-				"text/plain",
-			),
-			embedded,
-		},
-	}, nil
-}
+			buf: replay,
+		})
+	}
+	if opts.SSE {
+		sseOpts := []server.SSEOption{
+			server.WithBaseURL(opts.BaseURL),
+			server.WithStaticBasePath(opts.BasePath),
+			server.WithSSEEndpoint(opts.SSEPath),
+			server.WithMessageEndpoint(opts.MessagePath),
+		}
+		if opts.Heartbeat > 0 {
+			sseOpts = append(sseOpts, server.WithKeepAliveInterval(opts.Heartbeat))
+		}
+		sse := server.NewSSEServer(mcpServer, sseOpts...)
+		handle(opts.SSEPath, registry.TransportSSE, sse.SSEHandler())
+		handle(opts.MessagePath, registry.TransportSSE, sse.MessageHandler())
+	}
+	mux.Handle(opts.Endpoint("/debug/vars"), expvar.Handler())
 
-func handleAudioToolCall(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	const (
-		sampleRate = 8000
-		duration   = 1 // seconds
-		numSamples = sampleRate * duration
-	)
+	// Probes bypass the drain gate so /readyz can report the drain itself.
+	probes := &health{}
+	probes.add("shutdown", checkDraining(gate))
+	if opts.MaxSessions > 0 {
+		probes.add("sessions", checkSessionCap(sessions))
+	}
+	for _, dir := range splitList(opts.ReadyDirs) {
+		probes.add("dir:"+dir, checkDir(dir))
+	}
+	var upstreams []*upstreamCheck
+	for _, u := range splitList(opts.ReadyUpstreams) {
+		up := &upstreamCheck{url: u}
+		upstreams = append(upstreams, up)
+		probes.add("upstream:"+u, up.run)
+	}
+	mux.HandleFunc(opts.Endpoint("/healthz"), probes.handleLive)
+	mux.HandleFunc(opts.Endpoint("/readyz"), probes.handleReady)
 
-	// WAV header for 16-bit PCM mono
-	var buf bytes.Buffer
-	// RIFF header
-	buf.WriteString("RIFF")
-	binary.Write(&buf, binary.LittleEndian, uint32(36+numSamples*2))
-	buf.WriteString("WAVE")
-	// fmt chunk
-	buf.WriteString("fmt ")
-	binary.Write(&buf, binary.LittleEndian, uint32(16)) // Subchunk1Size
-	binary.Write(&buf, binary.LittleEndian, uint16(1))  // AudioFormat PCM
-	binary.Write(&buf, binary.LittleEndian, uint16(1))  // NumChannels
-	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
-	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2)) // ByteRate
-	binary.Write(&buf, binary.LittleEndian, uint16(2))            // BlockAlign
-	binary.Write(&buf, binary.LittleEndian, uint16(16))           // BitsPerSample
-	// data chunk
-	buf.WriteString("data")
-	binary.Write(&buf, binary.LittleEndian, uint32(numSamples*2))
-
-	// Write random samples
-	rand.Seed(time.Now().UnixNano())
-	for i := 0; i < numSamples; i++ {
-		sample := int16(rand.Intn(65536) - 32768)
-		binary.Write(&buf, binary.LittleEndian, sample)
-	}
-
-	data := buf.Bytes()
-	encoded := base64.StdEncoding.EncodeToString(data)
-
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			mcp.NewAudioContent(encoded, "audio/wav"),
-		},
-	}, nil
-}
+	if opts.WebhooksFile != "" {
+		ingress, err := loadWebhooks(opts.WebhooksFile, actions)
+		if err != nil {
+			log.Fatalf("Invalid configuration:\n%v", err)
+		}
+		ingress.routes(mux, opts.Endpoint("/hooks"))
+	}
 
-func handleStructuredContentCall(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	structuredData := map[string]interface{}{
-		"title":       "Sample Structured Content",
-		"description": "This is an example of structured content returned by a tool.",
-		"items": []map[string]interface{}{
-			{
-				"id":    1,
-				"name":  "Item One",
-				"value": 100,
-			},
-			{
-				"id":    2,
-				"name":  "Item Two",
-				"value": 200,
+	if opts.AdminToken != "" {
+		admin := &adminAPI{
+			token:    opts.AdminToken,
+			srv:      mcpServer,
+			sessions: sessions,
+			broker:   broker,
+			jobs:     jobs,
+			delivery: delivery,
+			disconnect: func(ctx context.Context, sessionID string) error {
+				return terminateSession(ctx, sessionStore, sessions, sessionID)
 			},
-		},
+		}
+		admin.routes(mux, opts.Endpoint("/admin"))
 	}
 
-	return mcp.NewToolResultStructured(map[string]any{"data": structuredData}, "Returned structured data"), nil
-}
+	// Every stream derives from streamsCtx, cancelling it ends the long-lived
+	// GET and SSE streams and the stdio transport during shutdown.
+	streamsCtx, closeStreams := context.WithCancel(context.Background())
+	done := make(chan error, 2)
+	go sessions.Run(streamsCtx)
+	go pruneSessions(streamsCtx, sessionStore, recordTTL(opts.IdleTTL))
+	go jobs.Run(streamsCtx)
 
-func resourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-	return []mcp.ResourceContents{
-		mcp.TextResourceContents{
-			URI:      "file://sample.txt",
-			MIMEType: "text/plain",
-			Text:     "Hello, this is a sample text file content.",
-		},
-	}, nil
-}
-
-func handleWithSchemaCall(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	output := OutputSchema{
-		Title:       "Sample Title",
-		Description: "This is a sample description.",
+	var httpServer *http.Server
+	if opts.HTTP() {
+		httpServer = &http.Server{
+			Addr:        opts.Addr,
+			Handler:     mux,
+			BaseContext: func(net.Listener) context.Context { return streamsCtx },
+		}
+		go func() {
+			if opts.Streamable {
+				log.Printf("Streamable HTTP on %s%s", opts.Addr, opts.Endpoint(opts.StreamablePath))
+			}
+			if opts.SSE {
+				log.Printf("SSE on %s%s, messages on %s", opts.Addr, opts.Endpoint(opts.SSEPath), opts.Endpoint(opts.MessagePath))
+			}
+			if opts.TLS() {
+				done <- httpServer.ListenAndServeTLS(opts.TLSCert, opts.TLSKey)
+				return
+			}
+			done <- httpServer.ListenAndServe()
+		}()
 	}
-	return mcp.NewToolResultStructured(output, "Data returned in structured format"), nil
-}
-
-func egPromptHandler(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
-	message, ok := request.Params.Arguments["message"]
-	if !ok {
-		message = "No message provided"
+	if opts.Stdio {
+		go func() {
+			log.Println("Serving on stdio")
+			ctx := registry.WithTransport(streamsCtx, registry.TransportStdio)
+			err := server.NewStdioServer(mcpServer).Listen(ctx, os.Stdin, os.Stdout)
+			// The stdio client closing its end only stops the server when
+			// there are no HTTP clients to keep serving.
+			if err == nil && httpServer != nil {
+				log.Println("Stdio client disconnected, still serving HTTP")
+				return
+			}
+			done <- err
+		}()
 	}
 
-	messages := []mcp.PromptMessage{
-		{
-			Role:    mcp.RoleAssistant,
-			Content: mcp.NewTextContent("Your message: " + message),
-		},
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-done:
+		// The stdio client closed its end with no HTTP transport enabled,
+		// or the HTTP listener failed.
+		if err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, context.Canceled) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	case <-sig:
+		// A second signal skips the drain.
+		go func() {
+			<-sig
+			log.Println("Forced exit.")
+			os.Exit(1)
+		}()
+	}
+	gracefulShutdown(mcpServer, gate, httpServer, closeStreams, m, opts.DrainTimeout)
+	for _, up := range upstreams {
+		up.close()
 	}
-	return &mcp.GetPromptResult{
-		Result:      mcp.Result{},
-		Description: "Yeh le tera prompt result",
-		Messages:    messages,
-	}, nil
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// metrics holds the server's counters. They are published through expvar, so
+// they can be scraped from /debug/vars, and are logged once more on exit.
+type metrics struct {
+	sessions  *expvar.Int
+	requests  *expvar.Map // method -> count
+	errors    *expvar.Map // method -> count
+	toolCalls *expvar.Map // tool name -> count
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		sessions:  expvar.NewInt("mcp_sessions_active"),
+		requests:  expvar.NewMap("mcp_requests_total"),
+		errors:    expvar.NewMap("mcp_errors_total"),
+		toolCalls: expvar.NewMap("mcp_tool_calls_total"),
+	}
+}
+
+// register feeds the counters from the server's hooks.
+func (m *metrics) register(hooks *server.Hooks) {
+	hooks.AddOnRegisterSession(func(ctx context.Context, sess server.ClientSession) {
+		m.sessions.Add(1)
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, sess server.ClientSession) {
+		m.sessions.Add(-1)
+	})
+	hooks.AddBeforeAny(func(ctx context.Context, id any, method mcp.MCPMethod, message any) {
+		m.requests.Add(string(method), 1)
+	})
+	hooks.AddOnError(func(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
+		m.errors.Add(string(method), 1)
+	})
+	hooks.AddBeforeCallTool(func(ctx context.Context, id any, req *mcp.CallToolRequest) {
+		m.toolCalls.Add(req.Params.Name, 1)
+	})
+}
+
+// flush writes the final value of every counter to the log.
+func (m *metrics) flush() {
+	var b strings.Builder
+	for _, v := range []struct {
+		name string
+		val  expvar.Var
+	}{
+		{"sessions_active", m.sessions},
+		{"requests_total", m.requests},
+		{"errors_total", m.errors},
+		{"tool_calls_total", m.toolCalls},
+	} {
+		fmt.Fprintf(&b, " %s=%s", v.name, v.val.String())
+	}
+	log.Printf("[metrics]%s", b.String())
+}
@@ -0,0 +1,193 @@
+// Package outbox holds notifications for clients that are not connected and
+// hands them over, in order, when they come back.
+//
+// Messages are addressed to a client identity, such as the authenticated
+// principal or the client name, rather than to a session: session IDs change
+// on every reconnect, identities do not. With a directory the queues are
+// written to one JSON file per identity and survive a restart; without one
+// they live in memory.
+package outbox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a queued notification.
+type Message struct {
+	Method    string         `json:"method"`
+	Params    map[string]any `json:"params,omitempty"`
+	QueuedAt  time.Time      `json:"queuedAt"`
+	ExpiresAt time.Time      `json:"expiresAt,omitzero"`
+}
+
+func (m Message) expired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && !now.Before(m.ExpiresAt)
+}
+
+// queueFile is the on-disk form of one identity's queue.
+type queueFile struct {
+	Identity string    `json:"identity"`
+	Messages []Message `json:"messages"`
+}
+
+// Outbox is a set of bounded per-identity queues.
+type Outbox struct {
+	dir   string
+	depth int
+	ttl   time.Duration
+
+	mu     sync.Mutex
+	queues map[string][]Message
+}
+
+// New returns an outbox that keeps at most depth messages per identity, each
+// for at most ttl (0 for no expiry). With a non-empty dir, queues are
+// persisted there and the ones already present are loaded.
+func New(dir string, depth int, ttl time.Duration) (*Outbox, error) {
+	o := &Outbox{dir: dir, depth: depth, ttl: ttl, queues: make(map[string][]Message)}
+	if dir == "" {
+		return o, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create outbox dir: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var qf queueFile
+		if err := json.Unmarshal(data, &qf); err != nil {
+			return nil, fmt.Errorf("decode outbox %s: %w", e.Name(), err)
+		}
+		o.queues[qf.Identity] = qf.Messages
+	}
+	return o, nil
+}
+
+// Push appends a notification to identity's queue. If the queue is full the
+// oldest message is discarded; Push returns how many were.
+func (o *Outbox) Push(identity, method string, params map[string]any) (int, error) {
+	now := time.Now()
+	m := Message{Method: method, Params: params, QueuedAt: now}
+	if o.ttl > 0 {
+		m.ExpiresAt = now.Add(o.ttl)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	q := append(o.liveLocked(identity, now), m)
+	dropped := 0
+	if over := len(q) - o.depth; over > 0 {
+		q = slices.Delete(q, 0, over)
+		dropped = over
+	}
+	return dropped, o.saveLocked(identity, q)
+}
+
+// Flush hands identity's messages to send, oldest first, and removes the ones
+// it accepts. It stops at the first error so that order is kept; the failed
+// message and those after it stay queued for the next Flush.
+func (o *Outbox) Flush(identity string, send func(Message) error) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	q := o.liveLocked(identity, time.Now())
+	sent := 0
+	var sendErr error
+	for _, m := range q {
+		if sendErr = send(m); sendErr != nil {
+			break
+		}
+		sent++
+	}
+	if err := o.saveLocked(identity, q[sent:]); err != nil {
+		return sent, err
+	}
+	return sent, sendErr
+}
+
+// Len returns the number of live messages queued for identity.
+func (o *Outbox) Len(identity string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.liveLocked(identity, time.Now()))
+}
+
+// Depths returns the number of messages queued per identity, expired ones
+// included until they are next touched.
+func (o *Outbox) Depths() map[string]int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := make(map[string]int, len(o.queues))
+	for id, q := range o.queues {
+		out[id] = len(q)
+	}
+	return out
+}
+
+// liveLocked returns identity's queue without expired messages.
+func (o *Outbox) liveLocked(identity string, now time.Time) []Message {
+	return slices.DeleteFunc(slices.Clone(o.queues[identity]), func(m Message) bool { return m.expired(now) })
+}
+
+// saveLocked replaces identity's queue, on disk as well when persisting.
+func (o *Outbox) saveLocked(identity string, q []Message) error {
+	if len(q) == 0 {
+		delete(o.queues, identity)
+	} else {
+		o.queues[identity] = q
+	}
+	if o.dir == "" {
+		return nil
+	}
+
+	path := o.path(identity)
+	if len(q) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(queueFile{Identity: identity, Messages: q}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(o.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// path names identity's file by a hash, since identities are arbitrary
+// strings chosen by clients or proxies.
+func (o *Outbox) path(identity string) string {
+	sum := sha256.Sum256([]byte(identity))
+	return filepath.Join(o.dir, hex.EncodeToString(sum[:16])+".json")
+}
@@ -0,0 +1,277 @@
+// Package pubsub delivers server notifications by topic. Sessions subscribe
+// to named topics and only receive what is published to those. Every session
+// has its own bounded queue, drained by its own goroutine, so one slow client
+// cannot hold up delivery to the others.
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// MethodTopicMessage is the notification method every topic message is sent
+// with.
+const MethodTopicMessage = "notifications/topic"
+
+// DropPolicy says what happens when a session's queue is full.
+type DropPolicy string
+
+const (
+	// DropOldest discards the oldest queued message to make room.
+	DropOldest DropPolicy = "drop-oldest"
+	// DropNewest discards the message being published.
+	DropNewest DropPolicy = "drop-newest"
+)
+
+// ParseDropPolicy validates a policy name.
+func ParseDropPolicy(s string) (DropPolicy, error) {
+	switch p := DropPolicy(s); p {
+	case DropOldest, DropNewest:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown drop policy %q, use %s or %s", s, DropOldest, DropNewest)
+}
+
+var errBadTopic = errors.New("topic must be 1 to 128 characters")
+
+// retryDelay is how long delivery waits when a session's own notification
+// channel is full before trying the same message again.
+const retryDelay = 50 * time.Millisecond
+
+// Message is a published message. It is sent as the params of a
+// MethodTopicMessage notification, along with a "dropped" count of the
+// messages the session lost since the previous one it received.
+type Message struct {
+	Topic string `json:"topic"`
+	Seq   uint64 `json:"seq"` // counts the messages published to Topic
+	Data  any    `json:"data"`
+}
+
+// Broker keeps the subscriptions of every session and delivers published
+// messages to them.
+type Broker struct {
+	srv    *server.MCPServer
+	buffer int
+	policy DropPolicy
+
+	mu       sync.Mutex
+	topics   map[string]map[string]struct{} // topic -> subscribed session IDs
+	seqs     map[string]uint64              // topic -> last sequence number
+	sessions map[string]*queue              // session ID -> delivery queue
+
+	dropped atomic.Int64
+}
+
+// NewBroker returns a broker that queues up to buffer messages per session.
+func NewBroker(srv *server.MCPServer, buffer int, policy DropPolicy) *Broker {
+	return &Broker{
+		srv:      srv,
+		buffer:   buffer,
+		policy:   policy,
+		topics:   make(map[string]map[string]struct{}),
+		seqs:     make(map[string]uint64),
+		sessions: make(map[string]*queue),
+	}
+}
+
+// Register drops a session's subscriptions and queue when it goes away.
+func (b *Broker) Register(hooks *server.Hooks) {
+	hooks.AddOnUnregisterSession(func(ctx context.Context, sess server.ClientSession) {
+		b.removeSession(sess.SessionID())
+	})
+}
+
+// Subscribe adds the session to topic.
+func (b *Broker) Subscribe(sessionID, topic string) error {
+	if len(topic) == 0 || len(topic) > 128 {
+		return errBadTopic
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[string]struct{})
+	}
+	b.topics[topic][sessionID] = struct{}{}
+	if _, ok := b.sessions[sessionID]; !ok {
+		q := newQueue(b.buffer, b.policy)
+		b.sessions[sessionID] = q
+		go b.deliver(sessionID, q)
+	}
+	return nil
+}
+
+// Unsubscribe removes the session from topic.
+func (b *Broker) Unsubscribe(sessionID, topic string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.topics[topic], sessionID)
+	if len(b.topics[topic]) == 0 {
+		delete(b.topics, topic)
+	}
+}
+
+// Topics returns the topics the session is subscribed to, sorted.
+func (b *Broker) Topics(sessionID string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out []string
+	for topic, subs := range b.topics {
+		if _, ok := subs[sessionID]; ok {
+			out = append(out, topic)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Publish queues data for every session subscribed to topic and returns how
+// many sessions that was. It never blocks on a slow client.
+func (b *Broker) Publish(topic string, data any) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seqs[topic]++
+	msg := Message{Topic: topic, Seq: b.seqs[topic], Data: data}
+	for sid := range b.topics[topic] {
+		if q, ok := b.sessions[sid]; ok && !q.push(msg) {
+			b.dropped.Add(1)
+		}
+	}
+	return len(b.topics[topic])
+}
+
+// Dropped returns the number of messages dropped so far across sessions.
+func (b *Broker) Dropped() int64 {
+	return b.dropped.Load()
+}
+
+func (b *Broker) removeSession(sessionID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for topic, subs := range b.topics {
+		delete(subs, sessionID)
+		if len(subs) == 0 {
+			delete(b.topics, topic)
+		}
+	}
+	if q, ok := b.sessions[sessionID]; ok {
+		q.close()
+		delete(b.sessions, sessionID)
+	}
+}
+
+// deliver sends queued messages to one session until its queue is closed.
+// While the session's notification channel is full the message is retried,
+// so a slow client backs up into its queue, where the drop policy applies.
+func (b *Broker) deliver(sessionID string, q *queue) {
+	for {
+		msg, dropped, ok := q.pop()
+		if !ok {
+			return
+		}
+		params := map[string]any{
+			"topic": msg.Topic,
+			"seq":   msg.Seq,
+			"data":  msg.Data,
+		}
+		if dropped > 0 {
+			params["dropped"] = dropped
+		}
+		for {
+			err := b.srv.SendNotificationToSpecificClient(sessionID, MethodTopicMessage, params)
+			if errors.Is(err, server.ErrNotificationChannelBlocked) && !q.isClosed() {
+				time.Sleep(retryDelay)
+				continue
+			}
+			if err != nil && !q.isClosed() {
+				log.Printf("[pubsub] %s: deliver %s#%d: %v", sessionID, msg.Topic, msg.Seq, err)
+			}
+			break
+		}
+	}
+}
+
+// queue is a bounded FIFO of messages for one session.
+type queue struct {
+	size   int
+	policy DropPolicy
+
+	mu      sync.Mutex
+	items   []Message
+	dropped int // since the last pop
+	closed  bool
+	ready   chan struct{} // signalled when items or closed change
+}
+
+func newQueue(size int, policy DropPolicy) *queue {
+	return &queue{size: size, policy: policy, ready: make(chan struct{}, 1)}
+}
+
+// push adds msg and reports false if a message had to be dropped.
+func (q *queue) push(msg Message) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return true
+	}
+	kept := true
+	if len(q.items) >= q.size {
+		kept = false
+		q.dropped++
+		if q.policy == DropNewest {
+			return false
+		}
+		q.items = q.items[1:]
+	}
+	q.items = append(q.items, msg)
+	q.signal()
+	return kept
+}
+
+// pop waits for the next message. It also returns how many messages were
+// dropped since the previous pop. ok is false once the queue is closed.
+func (q *queue) pop() (msg Message, dropped int, ok bool) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return Message{}, 0, false
+		}
+		if len(q.items) > 0 {
+			msg = q.items[0]
+			q.items = q.items[1:]
+			dropped, q.dropped = q.dropped, 0
+			q.mu.Unlock()
+			return msg, dropped, true
+		}
+		q.mu.Unlock()
+		<-q.ready
+	}
+}
+
+func (q *queue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.signal()
+}
+
+func (q *queue) isClosed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.closed
+}
+
+func (q *queue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,135 @@
+package registry
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type (
+	transportKey  struct{}
+	remoteAddrKey struct{}
+	principalKey  struct{}
+	connKey       struct{}
+)
+
+// WithTransport tags ctx with the transport its requests arrive on. Track
+// does this for HTTP; stdio has to tag the context passed to Listen.
+func WithTransport(ctx context.Context, transport string) context.Context {
+	return context.WithValue(ctx, transportKey{}, transport)
+}
+
+func transportFromContext(ctx context.Context) string {
+	t, _ := ctx.Value(transportKey{}).(string)
+	return t
+}
+
+// WithPrincipal records the authenticated user of a request.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the user recorded by WithPrincipal, or "".
+func PrincipalFromContext(ctx context.Context) string {
+	p, _ := ctx.Value(principalKey{}).(string)
+	return p
+}
+
+// RequestSessionID returns the session an HTTP request belongs to: streamable
+// HTTP sends it in the Mcp-Session-Id header, legacy SSE in the sessionId
+// query parameter of the message endpoint.
+func RequestSessionID(r *http.Request) string {
+	if id := r.Header.Get(server.HeaderKeySessionID); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("sessionId")
+}
+
+// conn is a long-lived request, an SSE or GET stream, that can be ended from
+// outside by cancelling its context.
+type conn struct {
+	cancel    context.CancelFunc
+	sessionID string
+}
+
+// Track wraps an HTTP transport. It records the transport, remote address and
+// principal of every request, refuses new sessions while the registry is
+// full and makes every GET stream closable through Disconnect.
+func (r *Registry) Track(transport string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sessionID := RequestSessionID(req)
+		if sessionID == "" && r.Full() {
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "too many sessions", http.StatusServiceUnavailable)
+			return
+		}
+
+		ctx := WithTransport(req.Context(), transport)
+		ctx = context.WithValue(ctx, remoteAddrKey{}, req.RemoteAddr)
+		if r.opts.PrincipalHeader != "" {
+			if p := req.Header.Get(r.opts.PrincipalHeader); p != "" {
+				ctx = WithPrincipal(ctx, p)
+			}
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			c := &conn{cancel: cancel, sessionID: sessionID}
+			if c.sessionID != "" {
+				r.mu.Lock()
+				r.addConnLocked(c)
+				r.mu.Unlock()
+			}
+			defer r.removeConn(c)
+			next.ServeHTTP(w, req.WithContext(context.WithValue(ctx, connKey{}, c)))
+		case http.MethodDelete:
+			// mcp-go terminates the session on DELETE but never unregisters
+			// it, so do that once the transport has accepted the request.
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, req.WithContext(ctx))
+			if sessionID != "" && sw.status == http.StatusOK {
+				log.Printf("[sessions] %s closed by the client", sessionID)
+				r.Disconnect(ctx, sessionID)
+			}
+		default:
+			next.ServeHTTP(w, req.WithContext(ctx))
+		}
+	})
+}
+
+func (r *Registry) addConnLocked(c *conn) {
+	if r.conns[c.sessionID] == nil {
+		r.conns[c.sessionID] = make(map[*conn]struct{})
+	}
+	r.conns[c.sessionID][c] = struct{}{}
+}
+
+func (r *Registry) removeConn(c *conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c.sessionID == "" {
+		return
+	}
+	delete(r.conns[c.sessionID], c)
+	if len(r.conns[c.sessionID]) == 0 {
+		delete(r.conns, c.sessionID)
+	}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,279 @@
+// Package registry keeps track of the sessions connected to an MCP server on
+// any transport: who the client is, where it connects from, how busy it is
+// and which streams it holds open. It is fed by the server's hooks and by an
+// HTTP middleware, and can end sessions that sit idle or exceed a cap.
+package registry
+
+import (
+	"context"
+	"log"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Transport names recorded for each session.
+const (
+	TransportStdio      = "stdio"
+	TransportSSE        = "sse"
+	TransportStreamable = "streamable"
+)
+
+// Session is what the registry knows about one session.
+type Session struct {
+	ID               string           `json:"id"`
+	Transport        string           `json:"transport"`
+	ClientName       string           `json:"clientName,omitempty"`
+	ClientVersion    string           `json:"clientVersion,omitempty"`
+	ProtocolVersion  string           `json:"protocolVersion,omitempty"`
+	RemoteAddr       string           `json:"remoteAddr,omitempty"`
+	Principal        string           `json:"principal,omitempty"`
+	ConnectedAt      time.Time        `json:"connectedAt"`
+	LastActivity     time.Time        `json:"lastActivity"`
+	Requests         int64            `json:"requests"`
+	RequestsByMethod map[string]int64 `json:"requestsByMethod,omitempty"`
+	Streams          int              `json:"streams"`
+}
+
+// Identity names the client behind a session independently of the session
+// ID: the authenticated principal if there is one, otherwise the client name
+// it sent in initialize. It is empty until one of them is known.
+func (s *Session) Identity() string {
+	if s.Principal != "" {
+		return s.Principal
+	}
+	return s.ClientName
+}
+
+// Options configure a Registry.
+type Options struct {
+	// IdleTTL is how long a session may go without a request before it is
+	// evicted. Zero disables eviction.
+	IdleTTL time.Duration
+	// MaxSessions caps the number of sessions; new ones are refused while
+	// the cap is reached. Zero means no cap.
+	MaxSessions int
+	// PrincipalHeader names a request header carrying the authenticated
+	// user, set by an authenticating proxy in front of the server.
+	PrincipalHeader string
+	// Evict ends an idle session. It defaults to Registry.Disconnect.
+	Evict func(ctx context.Context, sessionID string)
+}
+
+// Registry records every session of one MCPServer.
+type Registry struct {
+	srv  *server.MCPServer
+	opts Options
+
+	mu       sync.RWMutex
+	sessions map[string]*Session
+	conns    map[string]map[*conn]struct{} // sessionID -> open streams
+}
+
+// New returns a registry for srv. Call Register to feed it from the hooks
+// srv was created with.
+func New(srv *server.MCPServer, opts Options) *Registry {
+	r := &Registry{
+		srv:      srv,
+		opts:     opts,
+		sessions: make(map[string]*Session),
+		conns:    make(map[string]map[*conn]struct{}),
+	}
+	if r.opts.Evict == nil {
+		r.opts.Evict = r.Disconnect
+	}
+	return r
+}
+
+// Register wires the registry into hooks.
+func (r *Registry) Register(hooks *server.Hooks) {
+	hooks.AddOnRegisterSession(func(ctx context.Context, sess server.ClientSession) {
+		now := time.Now()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		// Streamable HTTP registers a session only after its initialize
+		// request was answered, so the entry may exist already.
+		s := r.entryLocked(sess.SessionID())
+		s.Transport = transportFromContext(ctx)
+		s.RemoteAddr, _ = ctx.Value(remoteAddrKey{}).(string)
+		s.Principal = PrincipalFromContext(ctx)
+		s.ConnectedAt = now
+		s.LastActivity = now
+		// An SSE stream only learns its session ID here.
+		if c, ok := ctx.Value(connKey{}).(*conn); ok && c.sessionID == "" {
+			c.sessionID = sess.SessionID()
+			r.addConnLocked(c)
+		}
+		log.Printf("[sessions] + %s %s (now %d)", s.Transport, s.ID, len(r.sessions))
+	})
+
+	hooks.AddOnUnregisterSession(func(ctx context.Context, sess server.ClientSession) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.sessions, sess.SessionID())
+		log.Printf("[sessions] - %s (now %d)", sess.SessionID(), len(r.sessions))
+	})
+
+	hooks.AddAfterInitialize(func(ctx context.Context, id any, req *mcp.InitializeRequest, res *mcp.InitializeResult) {
+		sess := server.ClientSessionFromContext(ctx)
+		if sess == nil || sess.SessionID() == "" {
+			return
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		s := r.entryLocked(sess.SessionID())
+		s.ClientName = req.Params.ClientInfo.Name
+		s.ClientVersion = req.Params.ClientInfo.Version
+		s.ProtocolVersion = res.ProtocolVersion
+	})
+
+	hooks.AddBeforeAny(func(ctx context.Context, id any, method mcp.MCPMethod, message any) {
+		sess := server.ClientSessionFromContext(ctx)
+		if sess == nil {
+			return
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		s, ok := r.sessions[sess.SessionID()]
+		if !ok && method != mcp.MethodInitialize {
+			return
+		}
+		if !ok {
+			s = r.entryLocked(sess.SessionID())
+		}
+		s.LastActivity = time.Now()
+		s.Requests++
+		s.RequestsByMethod[string(method)]++
+	})
+}
+
+func (r *Registry) entryLocked(id string) *Session {
+	s, ok := r.sessions[id]
+	if !ok {
+		s = &Session{ID: id, RequestsByMethod: make(map[string]int64)}
+		r.sessions[id] = s
+	}
+	return s
+}
+
+// Get returns a copy of the session with the given ID.
+func (r *Registry) Get(id string) (Session, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+	return r.copyLocked(s), true
+}
+
+// List returns a copy of every session, oldest first.
+func (r *Registry) List() []Session {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Session, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		out = append(out, r.copyLocked(s))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ConnectedAt.Before(out[j].ConnectedAt)
+	})
+	return out
+}
+
+// SessionsOf returns the IDs of the connected sessions with the given
+// identity: registered with the server and, on streamable HTTP, holding a GET
+// stream open.
+func (r *Registry) SessionsOf(identity string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var ids []string
+	for _, s := range r.sessions {
+		if identity == "" || s.Identity() != identity || s.ConnectedAt.IsZero() {
+			continue
+		}
+		// mcp-go keeps a streamable HTTP session registered, and buffers
+		// what is sent to it, after its GET stream has gone; only a session
+		// with an open stream is reached.
+		if s.Transport == TransportStreamable && len(r.conns[s.ID]) == 0 {
+			continue
+		}
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func (r *Registry) copyLocked(s *Session) Session {
+	c := *s
+	c.RequestsByMethod = maps.Clone(s.RequestsByMethod)
+	c.Streams = len(r.conns[s.ID])
+	return c
+}
+
+// Count returns the number of sessions.
+func (r *Registry) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.sessions)
+}
+
+// MaxSessions returns the configured cap, zero if there is none.
+func (r *Registry) MaxSessions() int {
+	return r.opts.MaxSessions
+}
+
+// Full reports whether the session cap is reached.
+func (r *Registry) Full() bool {
+	return r.opts.MaxSessions > 0 && r.Count() >= r.opts.MaxSessions
+}
+
+// Disconnect closes the session's open streams and unregisters it from the
+// server. It does not stop a streamable HTTP client from using the session
+// again; delete it from the session store for that.
+func (r *Registry) Disconnect(ctx context.Context, sessionID string) {
+	r.mu.RLock()
+	for c := range r.conns[sessionID] {
+		c.cancel()
+	}
+	r.mu.RUnlock()
+	r.srv.UnregisterSession(ctx, sessionID)
+}
+
+// Run evicts idle sessions until ctx is done. It returns at once when no
+// IdleTTL is configured. A session's open streams do not count as activity,
+// only its requests do. The stdio session is never evicted.
+func (r *Registry) Run(ctx context.Context) {
+	if r.opts.IdleTTL <= 0 {
+		return
+	}
+	interval := max(r.opts.IdleTTL/4, time.Second)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, id := range r.idle(now) {
+				log.Printf("[sessions] evicting %s, idle for more than %s", id, r.opts.IdleTTL)
+				r.opts.Evict(ctx, id)
+			}
+		}
+	}
+}
+
+func (r *Registry) idle(now time.Time) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var ids []string
+	for id, s := range r.sessions {
+		if s.Transport != TransportStdio && now.Sub(s.LastActivity) > r.opts.IdleTTL {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
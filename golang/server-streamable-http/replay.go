@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const headerLastEventID = "Last-Event-ID"
+
+// sseEvent is one event written to a session's GET stream, stored exactly as
+// it went over the wire minus the id line.
+type sseEvent struct {
+	id      uint64
+	payload []byte
+}
+
+// eventLog is the replay buffer of a single session.
+type eventLog struct {
+	lastID  uint64
+	events  []sseEvent
+	release *time.Timer // drops the log once its stream has been gone a while
+}
+
+// replayBuffer numbers the events sent on each session's GET stream and keeps
+// the most recent ones, so a client that lost its stream can ask for what it
+// missed with Last-Event-ID. The buffer lives in process memory: a client
+// that reconnects to another replica gets a fresh stream without replay.
+type replayBuffer struct {
+	size int
+
+	mu       sync.Mutex
+	sessions map[string]*eventLog
+}
+
+func newReplayBuffer(size int) *replayBuffer {
+	return &replayBuffer{
+		size:     size,
+		sessions: make(map[string]*eventLog),
+	}
+}
+
+// append stores payload for the session and returns its event ID.
+func (b *replayBuffer) append(sessionID string, payload []byte) uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	el, ok := b.sessions[sessionID]
+	if !ok {
+		el = &eventLog{}
+		b.sessions[sessionID] = el
+	}
+	el.lastID++
+	el.events = append(el.events, sseEvent{id: el.lastID, payload: bytes.Clone(payload)})
+	if len(el.events) > b.size {
+		el.events = el.events[len(el.events)-b.size:]
+	}
+	return el.lastID
+}
+
+// since returns the buffered events after lastID. complete is false when some
+// of those events have already been evicted.
+func (b *replayBuffer) since(sessionID string, lastID uint64) (events []sseEvent, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	el, ok := b.sessions[sessionID]
+	if !ok {
+		return nil, lastID == 0
+	}
+	for _, ev := range el.events {
+		if ev.id > lastID {
+			events = append(events, ev)
+		}
+	}
+	complete = len(el.events) == 0 || el.events[0].id <= lastID+1
+	return events, complete
+}
+
+func (b *replayBuffer) drop(sessionID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if el, ok := b.sessions[sessionID]; ok && el.release != nil {
+		el.release.Stop()
+	}
+	delete(b.sessions, sessionID)
+}
+
+// replayGrace is how long a buffer outlives the GET stream that filled it,
+// for the client to come back with Last-Event-ID.
+const replayGrace = time.Minute
+
+// register releases a session's buffer replayGrace after the session is
+// unregistered from the server, unless it registers again first. mcp-go
+// unregisters a streamable session when its GET stream drops, which is just
+// when its client reconnects and asks for the events it missed.
+func (b *replayBuffer) register(hooks *server.Hooks) {
+	hooks.AddOnRegisterSession(func(ctx context.Context, sess server.ClientSession) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if el, ok := b.sessions[sess.SessionID()]; ok && el.release != nil {
+			el.release.Stop()
+			el.release = nil
+		}
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, sess server.ClientSession) {
+		id := sess.SessionID()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		el, ok := b.sessions[id]
+		if !ok {
+			return
+		}
+		if el.release != nil {
+			el.release.Stop()
+		}
+		var release *time.Timer
+		release = time.AfterFunc(replayGrace, func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if el, ok := b.sessions[id]; ok && el.release == release {
+				delete(b.sessions, id)
+			}
+		})
+		el.release = release
+	})
+}
+
+// replayHandler adds event IDs to the GET stream of every session and, when a
+// client reconnects with Last-Event-ID, resends the events it missed before
+// anything new.
+type replayHandler struct {
+	next http.Handler
+	buf  *replayBuffer
+}
+
+func (h *replayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.Header.Get(server.HeaderKeySessionID)
+	if sessionID == "" {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		h.next.ServeHTTP(w, r)
+		h.buf.drop(sessionID)
+	case http.MethodGet:
+		ew := &eventWriter{ResponseWriter: w, sessionID: sessionID, buf: h.buf}
+		if last := r.Header.Get(headerLastEventID); last != "" {
+			lastID, err := strconv.ParseUint(last, 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+				return
+			}
+			missed, complete := h.buf.since(sessionID, lastID)
+			if !complete {
+				log.Printf("[replay] %s: events after %d partly evicted, replaying %d", sessionID, lastID, len(missed))
+			}
+			ew.pending = missed
+		}
+		h.next.ServeHTTP(ew, r)
+	default:
+		h.next.ServeHTTP(w, r)
+	}
+}
+
+// eventWriter wraps the response of a GET stream. It splits what mcp-go
+// writes into SSE events, records each one other than heartbeat pings in the
+// replay buffer and prefixes it with an id line.
+type eventWriter struct {
+	http.ResponseWriter
+	sessionID string
+	buf       *replayBuffer
+
+	pending []sseEvent // events to replay once the stream is open
+	partial []byte     // bytes of an event not yet terminated by a blank line
+	stream  bool
+}
+
+func (e *eventWriter) WriteHeader(code int) {
+	e.stream = code == http.StatusOK &&
+		strings.HasPrefix(e.Header().Get("Content-Type"), "text/event-stream")
+	e.ResponseWriter.WriteHeader(code)
+	if !e.stream {
+		return
+	}
+	for _, ev := range e.pending {
+		if err := e.writeEvent(ev.id, ev.payload); err != nil {
+			log.Printf("[replay] %s: resend event %d: %v", e.sessionID, ev.id, err)
+			break
+		}
+	}
+	e.pending = nil
+}
+
+func (e *eventWriter) Write(p []byte) (int, error) {
+	if !e.stream {
+		return e.ResponseWriter.Write(p)
+	}
+	e.partial = append(e.partial, p...)
+	for {
+		end := bytes.Index(e.partial, []byte("\n\n"))
+		if end < 0 {
+			break
+		}
+		payload := e.partial[:end+2]
+		e.partial = e.partial[end+2:]
+		if isPing(payload) {
+			// Heartbeats are only worth something live; numbering and
+			// keeping them would push real events out of the buffer.
+			if _, err := e.ResponseWriter.Write(payload); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		id := e.buf.append(e.sessionID, payload)
+		if err := e.writeEvent(id, payload); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// isPing reports whether an SSE event carries a ping request, which is what
+// mcp-go sends as the GET stream's heartbeat.
+func isPing(payload []byte) bool {
+	for line := range bytes.Lines(payload) {
+		data, ok := bytes.CutPrefix(bytes.TrimRight(line, "\r\n"), []byte("data:"))
+		if !ok {
+			continue
+		}
+		var msg struct {
+			Method string `json:"method"`
+		}
+		if json.Unmarshal(data, &msg) == nil && msg.Method == string(mcp.MethodPing) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *eventWriter) writeEvent(id uint64, payload []byte) error {
+	if _, err := fmt.Fprintf(e.ResponseWriter, "id: %d\n", id); err != nil {
+		return err
+	}
+	_, err := e.ResponseWriter.Write(payload)
+	return err
+}
+
+func (e *eventWriter) Flush() {
+	if f, ok := e.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (e *eventWriter) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
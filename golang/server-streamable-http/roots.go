@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// rootsRequestTimeout bounds how long a tool waits for the client to answer
+// roots/list. The request travels over the client's GET stream, so a client
+// that never opened one would otherwise block the call forever.
+const rootsRequestTimeout = 10 * time.Second
+
+var errNoRoots = errors.New("client has not declared any roots")
+
+// sessionRoots keeps track of which sessions advertised the roots capability
+// and caches the directories they declared. The cache for a session is dropped
+// when the client sends notifications/roots/list_changed or disconnects.
+type sessionRoots struct {
+	mu      sync.Mutex
+	capable map[string]bool     // sessionID -> declared roots capability
+	roots   map[string][]string // sessionID -> cleaned root directories
+}
+
+func newSessionRoots() *sessionRoots {
+	return &sessionRoots{
+		capable: make(map[string]bool),
+		roots:   make(map[string][]string),
+	}
+}
+
+// register wires the cache into the server's hooks and notification handlers.
+func (r *sessionRoots) register(hooks *server.Hooks) {
+	hooks.AddAfterInitialize(func(ctx context.Context, id any, req *mcp.InitializeRequest, res *mcp.InitializeResult) {
+		sess := server.ClientSessionFromContext(ctx)
+		if sess == nil {
+			return
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.capable[sess.SessionID()] = req.Params.Capabilities.Roots != nil
+	})
+
+	hooks.AddOnUnregisterSession(func(ctx context.Context, sess server.ClientSession) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.capable, sess.SessionID())
+		delete(r.roots, sess.SessionID())
+	})
+}
+
+// setCapable records whether a session declared the roots capability. It is
+// used when the session was initialized by another process.
+func (r *sessionRoots) setCapable(sessionID string, capable bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.capable[sessionID] = capable
+}
+
+func (r *sessionRoots) handleListChanged(ctx context.Context, n mcp.JSONRPCNotification) {
+	sess := server.ClientSessionFromContext(ctx)
+	if sess == nil {
+		return
+	}
+	r.mu.Lock()
+	delete(r.roots, sess.SessionID())
+	r.mu.Unlock()
+	log.Printf("[roots] %s: list changed, cache cleared", sess.SessionID())
+}
+
+// get returns the root directories of the session in ctx, asking the client
+// for them if they are not cached yet.
+func (r *sessionRoots) get(ctx context.Context) ([]string, error) {
+	sess := server.ClientSessionFromContext(ctx)
+	if sess == nil {
+		return nil, server.ErrNoClientSession
+	}
+	sid := sess.SessionID()
+
+	r.mu.Lock()
+	capable := r.capable[sid]
+	cached, ok := r.roots[sid]
+	r.mu.Unlock()
+	if !capable {
+		return nil, errNoRoots
+	}
+	if ok {
+		return cached, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rootsRequestTimeout)
+	defer cancel()
+	res, err := server.ServerFromContext(ctx).RequestRoots(ctx, mcp.ListRootsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("roots/list: %w", err)
+	}
+
+	dirs := make([]string, 0, len(res.Roots))
+	for _, root := range res.Roots {
+		dir, err := rootDir(root.URI)
+		if err != nil {
+			log.Printf("[roots] %s: ignoring root %q: %v", sid, root.URI, err)
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+
+	r.mu.Lock()
+	r.roots[sid] = dirs
+	r.mu.Unlock()
+	return dirs, nil
+}
+
+// resolve turns path into an absolute, symlink-free path and checks that it
+// lies under one of the session's roots. Relative paths are taken relative to
+// the first root.
+func (r *sessionRoots) resolve(ctx context.Context, path string) (string, error) {
+	dirs, err := r.get(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(dirs) == 0 {
+		return "", errNoRoots
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dirs[0], path)
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+
+	for _, dir := range dirs {
+		if within(dir, resolved) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%s is outside the client's roots", path)
+}
+
+// rootDir converts a file:// root URI into a cleaned directory path.
+func rootDir(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	dir, err := filepath.EvalSymlinks(filepath.FromSlash(u.Path))
+	if err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func registerFileTools(s *CustomMCPServer, roots *sessionRoots) {
+	readFileTool := mcp.NewTool(
+		"read_file",
+		mcp.WithDescription("reads a text file under one of the client's roots"),
+		mcp.WithString(
+			"path",
+			mcp.Description("Absolute path, or a path relative to the first root"),
+			mcp.Required(),
+		),
+	)
+	s.AddTool(readFileTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		path, err := roots.resolve(ctx, request.GetString("path", ""))
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("access denied", err), nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("read failed", err), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	})
+
+	listDirTool := mcp.NewTool(
+		"list_directory",
+		mcp.WithDescription("lists a directory under one of the client's roots"),
+		mcp.WithString(
+			"path",
+			mcp.Description("Absolute path, or a path relative to the first root"),
+		),
+	)
+	s.AddTool(listDirTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		path, err := roots.resolve(ctx, request.GetString("path", "."))
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("access denied", err), nil
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("list failed", err), nil
+		}
+		var b strings.Builder
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() {
+				name += "/"
+			}
+			b.WriteString(name)
+			b.WriteString("\n")
+		}
+		return mcp.NewToolResultText(b.String()), nil
+	})
+
+	fileTemplate := mcp.NewResourceTemplate(
+		"file://{+path}",
+		"root_file",
+		mcp.WithTemplateDescription("Any file under one of the client's roots"),
+	)
+	s.AddResourceTemplate(fileTemplate, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		u, err := url.Parse(request.Params.URI)
+		if err != nil {
+			return nil, err
+		}
+		// file:///abs and file://localhost/abs name absolute paths; with the
+		// template's relative form, file://dir/name, the first segment ends
+		// up as the host and belongs in front of the path.
+		rel := u.Path
+		if u.Host != "" && u.Host != "localhost" {
+			rel = u.Host + u.Path
+		}
+		path, err := roots.resolve(ctx, filepath.FromSlash(rel))
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "text/plain",
+				Text:     string(data),
+			},
+		}, nil
+	})
+}
@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func at(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.UTC)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		spec string
+		from string
+		want string // empty when the schedule never fires
+	}{
+		// descriptors
+		{"@hourly", "2026-10-17 10:07:00", "2026-10-17 11:00:00"},
+		{"@daily", "2026-10-17 10:07:00", "2026-10-18 00:00:00"},
+		{"@midnight", "2026-10-17 00:00:00", "2026-10-18 00:00:00"},
+		{"@weekly", "2026-10-17 10:07:00", "2026-10-18 00:00:00"},
+		{"@monthly", "2026-10-17 10:07:00", "2026-11-01 00:00:00"},
+		{"@yearly", "2026-10-17 10:07:00", "2027-01-01 00:00:00"},
+		{"@every 90s", "2026-10-17 10:07:00.5", "2026-10-17 10:08:30"},
+
+		// lists, ranges, steps and names
+		{"*/15 * * * *", "2026-10-17 10:07:00", "2026-10-17 10:15:00"},
+		{"*/15 * * * *", "2026-10-17 10:45:00", "2026-10-17 11:00:00"},
+		{"5/20 * * * *", "2026-10-17 10:30:00", "2026-10-17 10:45:00"},
+		{"0 8-18/4 * * *", "2026-10-17 12:00:00", "2026-10-17 16:00:00"},
+		{"0,30 9 * * *", "2026-10-17 09:00:00", "2026-10-17 09:30:00"},
+		{"5 4 * jan,jul *", "2026-10-17 10:07:00", "2027-01-01 04:05:00"},
+		{"0 9 * * mon-fri", "2026-10-17 10:07:00", "2026-10-19 09:00:00"},
+		{"0 0 * * 7", "2026-10-17 10:07:00", "2026-10-18 00:00:00"},
+
+		// day of month and day of week
+		{"0 0 13 * fri", "2026-10-01 00:00:00", "2026-10-02 00:00:00"},
+		{"0 0 13 * fri", "2026-10-10 00:00:00", "2026-10-13 00:00:00"},
+		{"*/1 * * * 1", "2026-10-17 10:07:00", "2026-10-19 00:00:00"},
+		{"0 0 */2 * 1", "2026-10-20 00:00:00", "2026-11-09 00:00:00"},
+		{"0 0 1 * *", "2026-10-17 10:07:00", "2026-11-01 00:00:00"},
+
+		// gives up after five years
+		{"0 0 30 2 *", "2026-10-17 10:07:00", ""},
+		{"0 0 31 4 *", "2026-10-17 10:07:00", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec+" from "+tt.from, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			got := s.Next(at(tt.from))
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("Next = %s, want never", got)
+				}
+				return
+			}
+			if want := at(tt.want); !got.Equal(want) {
+				t.Errorf("Next = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"x * * * *",
+		"* * * foo *",
+		"@every 10ms",
+		"@every soon",
+		"@fortnightly",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	in := func(s string) time.Time {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			panic(err)
+		}
+		return t.In(loc)
+	}
+	tests := []struct {
+		spec string
+		from string
+		want string
+	}{
+		// spring forward: 02:00 EST is 03:00 EDT on 2026-03-08
+		{"0 0 * * *", "2026-03-08T00:00:00-05:00", "2026-03-09T00:00:00-04:00"},
+		{"30 2 * * *", "2026-03-08T00:00:00-05:00", "2026-03-09T02:30:00-04:00"},
+		{"0 3 * * *", "2026-03-08T00:00:00-05:00", "2026-03-08T03:00:00-04:00"},
+		{"*/30 * * * *", "2026-03-08T01:30:00-05:00", "2026-03-08T03:00:00-04:00"},
+
+		// fall back: 02:00 EDT is 01:00 EST on 2026-11-01, so 01:xx runs twice
+		{"7 * * * *", "2026-11-01T01:00:00-04:00", "2026-11-01T01:07:00-04:00"},
+		{"7 * * * *", "2026-11-01T01:07:00-04:00", "2026-11-01T01:07:00-05:00"},
+		{"7 * * * *", "2026-11-01T01:00:00-05:00", "2026-11-01T01:07:00-05:00"},
+		{"0 0 * * *", "2026-11-01T00:00:00-04:00", "2026-11-02T00:00:00-05:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec+" from "+tt.from, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			from := in(tt.from)
+			got := s.Next(from)
+			if want := in(tt.want); !got.Equal(want) {
+				t.Errorf("Next = %s, want %s", got, want)
+			}
+			if !got.After(from) {
+				t.Errorf("Next = %s, not after %s", got, from)
+			}
+		})
+	}
+}
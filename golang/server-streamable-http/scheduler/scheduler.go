@@ -0,0 +1,300 @@
+// Package scheduler runs jobs on cron schedules and keeps a short history of
+// their runs.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Overlap says what happens when a job is due while its previous run is
+// still going.
+type Overlap string
+
+const (
+	// OverlapSkip drops the new run and records it as skipped.
+	OverlapSkip Overlap = "skip"
+	// OverlapQueue runs once more as soon as the current run ends. Runs
+	// that come due in the meantime are folded into that one.
+	OverlapQueue Overlap = "queue"
+	// OverlapAllow starts the new run alongside the current one.
+	OverlapAllow Overlap = "allow"
+)
+
+// ParseOverlap validates an overlap policy name. The empty string means
+// OverlapSkip.
+func ParseOverlap(s string) (Overlap, error) {
+	switch o := Overlap(s); o {
+	case "":
+		return OverlapSkip, nil
+	case OverlapSkip, OverlapQueue, OverlapAllow:
+		return o, nil
+	}
+	return "", fmt.Errorf("unknown overlap policy %q, use %s, %s or %s", s, OverlapSkip, OverlapQueue, OverlapAllow)
+}
+
+// Job is a unit of work run on a schedule. Run returns a short description
+// of what it did, kept in the job's history.
+type Job struct {
+	Name     string
+	Spec     string
+	Schedule Schedule
+	// Jitter delays every run by a random duration up to this long, so jobs
+	// on the same schedule do not all fire at once.
+	Jitter  time.Duration
+	Overlap Overlap
+	// Timeout bounds a run, 0 for none.
+	Timeout time.Duration
+	Run     func(ctx context.Context) (string, error)
+}
+
+// Duration is a time.Duration that reads as "1.5s" in JSON.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// RunRecord describes one run of a job.
+type RunRecord struct {
+	Job       string    `json:"job"`
+	Trigger   string    `json:"trigger"` // "schedule", "manual" or "queued"
+	Scheduled time.Time `json:"scheduled"`
+	Started   time.Time `json:"started,omitzero"`
+	Duration  Duration  `json:"duration,omitempty"`
+	Skipped   bool      `json:"skipped,omitempty"`
+	Result    string    `json:"result,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// JobStatus is a job's configuration and current state.
+type JobStatus struct {
+	Name    string     `json:"name"`
+	Spec    string     `json:"schedule"`
+	Jitter  Duration   `json:"jitter,omitempty"`
+	Overlap Overlap    `json:"overlap"`
+	Timeout Duration   `json:"timeout,omitempty"`
+	Next    time.Time  `json:"next,omitzero"`
+	Running int        `json:"running"`
+	Last    *RunRecord `json:"last,omitempty"`
+}
+
+// ErrUnknownJob is returned for a job name that was never added.
+var ErrUnknownJob = errors.New("unknown job")
+
+// Scheduler runs jobs. Add every job before calling Run.
+type Scheduler struct {
+	history int
+
+	mu    sync.Mutex
+	jobs  map[string]*entry
+	order []string
+	ctx   context.Context // set by Run
+	wg    sync.WaitGroup
+}
+
+type entry struct {
+	job     Job
+	next    time.Time
+	running int
+	queued  bool
+	runs    []RunRecord // oldest first, at most Scheduler.history
+}
+
+// New returns a scheduler that keeps the last history runs of each job.
+func New(history int) *Scheduler {
+	return &Scheduler{history: history, jobs: make(map[string]*entry)}
+}
+
+// Add registers a job.
+func (s *Scheduler) Add(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job.Name == "" {
+		return errors.New("job has no name")
+	}
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("job %q defined twice", job.Name)
+	}
+	if job.Overlap == "" {
+		job.Overlap = OverlapSkip
+	}
+	s.jobs[job.Name] = &entry{job: job}
+	s.order = append(s.order, job.Name)
+	return nil
+}
+
+// Run fires the jobs on their schedules until ctx is cancelled, then waits
+// for the runs in progress, which see ctx cancelled too.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	entries := make([]*entry, 0, len(s.order))
+	for _, name := range s.order {
+		entries = append(entries, s.jobs[name])
+	}
+	s.mu.Unlock()
+
+	var loops sync.WaitGroup
+	for _, e := range entries {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			s.loop(ctx, e)
+		}()
+	}
+	loops.Wait()
+	s.wg.Wait()
+}
+
+// loop sleeps until each activation of one job, plus jitter, and fires it.
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	for {
+		next := e.job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("[scheduler] %s: schedule %q never fires again", e.job.Name, e.job.Spec)
+			return
+		}
+		s.mu.Lock()
+		e.next = next
+		s.mu.Unlock()
+
+		wait := time.Until(next)
+		if e.job.Jitter > 0 {
+			wait += rand.N(e.job.Jitter)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.fire(e, "schedule", next)
+	}
+}
+
+// Trigger runs a job now, subject to its overlap policy.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	e, ok := s.jobs[name]
+	started := s.ctx != nil
+	s.mu.Unlock()
+	if !ok {
+		return ErrUnknownJob
+	}
+	if !started {
+		return errors.New("scheduler is not running")
+	}
+	s.fire(e, "manual", time.Now())
+	return nil
+}
+
+// fire starts a run of e unless its overlap policy says otherwise.
+func (s *Scheduler) fire(e *entry, trigger string, scheduled time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return
+	}
+	if e.running > 0 {
+		switch e.job.Overlap {
+		case OverlapSkip:
+			s.recordLocked(e, RunRecord{Job: e.job.Name, Trigger: trigger, Scheduled: scheduled, Skipped: true, Result: "previous run still in progress"})
+			log.Printf("[scheduler] %s: skipped, previous run still in progress", e.job.Name)
+			return
+		case OverlapQueue:
+			e.queued = true
+			return
+		}
+	}
+	e.running++
+	s.wg.Add(1)
+	go s.run(e, trigger, scheduled)
+}
+
+func (s *Scheduler) run(e *entry, trigger string, scheduled time.Time) {
+	defer s.wg.Done()
+	for {
+		ctx := s.ctx
+		cancel := context.CancelFunc(func() {})
+		if e.job.Timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, e.job.Timeout)
+		}
+		rec := RunRecord{Job: e.job.Name, Trigger: trigger, Scheduled: scheduled, Started: time.Now()}
+		result, err := e.job.Run(ctx)
+		cancel()
+		took := time.Since(rec.Started)
+		rec.Duration = Duration(took)
+		rec.Result = result
+		if err != nil {
+			rec.Error = err.Error()
+			log.Printf("[scheduler] %s: failed after %s: %v", e.job.Name, took.Round(time.Millisecond), err)
+		} else {
+			log.Printf("[scheduler] %s: %s (%s)", e.job.Name, result, took.Round(time.Millisecond))
+		}
+
+		s.mu.Lock()
+		s.recordLocked(e, rec)
+		if !e.queued || s.ctx.Err() != nil {
+			e.running--
+			s.mu.Unlock()
+			return
+		}
+		e.queued = false
+		s.mu.Unlock()
+		trigger, scheduled = "queued", time.Now()
+	}
+}
+
+func (s *Scheduler) recordLocked(e *entry, rec RunRecord) {
+	e.runs = append(e.runs, rec)
+	if over := len(e.runs) - s.history; over > 0 {
+		e.runs = slices.Delete(e.runs, 0, over)
+	}
+}
+
+// Jobs returns the status of every job in the order they were added.
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]JobStatus, 0, len(s.order))
+	for _, name := range s.order {
+		e := s.jobs[name]
+		st := JobStatus{
+			Name:    e.job.Name,
+			Spec:    e.job.Spec,
+			Jitter:  Duration(e.job.Jitter),
+			Overlap: e.job.Overlap,
+			Timeout: Duration(e.job.Timeout),
+			Next:    e.next,
+			Running: e.running,
+		}
+		if n := len(e.runs); n > 0 {
+			last := e.runs[n-1]
+			st.Last = &last
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// History returns the recorded runs of a job, newest first.
+func (s *Scheduler) History(name string) ([]RunRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.jobs[name]
+	if !ok {
+		return nil, ErrUnknownJob
+	}
+	runs := slices.Clone(e.runs)
+	slices.Reverse(runs)
+	return runs, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/duaraghav8/gomcptest-server/state"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerScratchpadTools adds tools that keep notes in the caller's session
+// state, so a client can build up text over several calls.
+func registerScratchpadTools(s *CustomMCPServer, states *state.Manager) {
+	writeTool := mcp.NewTool(
+		"scratchpad_write",
+		mcp.WithDescription("stores a note in this session's scratchpad, replacing or appending to the note under the same key"),
+		mcp.WithString("key", mcp.Description("Name of the note"), mcp.Required()),
+		mcp.WithString("text", mcp.Description("Text to store"), mcp.Required()),
+		mcp.WithBoolean("append", mcp.Description("Append to the existing note instead of replacing it")),
+		mcp.WithNumber("ttl_seconds", mcp.Description("Forget the note after this many seconds")),
+	)
+	s.AddTool(writeTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pad, err := states.FromContext(ctx)
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("no session", err), nil
+		}
+		key := request.GetString("key", "")
+		text := request.GetString("text", "")
+		ttl := time.Duration(request.GetFloat("ttl_seconds", 0) * float64(time.Second))
+		note := text
+		if request.GetBool("append", false) {
+			// Read and write in one update, or concurrent appends would
+			// lose each other's text.
+			err = pad.Update(ctx, key, ttl, func(old json.RawMessage) (any, error) {
+				var prev string
+				if old != nil {
+					if err := json.Unmarshal(old, &prev); err != nil {
+						return nil, err
+					}
+				}
+				note = prev + text
+				return note, nil
+			})
+		} else {
+			err = pad.Set(ctx, key, text, ttl)
+		}
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("write failed", err), nil
+		}
+		loggerFromContext(ctx, "scratchpad").Debugf("%s: %d bytes", key, len(note))
+		return mcp.NewToolResultText("saved " + key), nil
+	})
+
+	readTool := mcp.NewTool(
+		"scratchpad_read",
+		mcp.WithDescription("reads a note from this session's scratchpad, or lists the notes when no key is given"),
+		mcp.WithString("key", mcp.Description("Name of the note")),
+	)
+	s.AddTool(readTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pad, err := states.FromContext(ctx)
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("no session", err), nil
+		}
+		key := request.GetString("key", "")
+		if key == "" {
+			keys, err := pad.Keys(ctx)
+			if err != nil {
+				return mcp.NewToolResultErrorFromErr("list failed", err), nil
+			}
+			return mcp.NewToolResultText(strings.Join(keys, "\n")), nil
+		}
+		var text string
+		found, err := pad.Get(ctx, key, &text)
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("read failed", err), nil
+		}
+		if !found {
+			return mcp.NewToolResultError("no note named " + key), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	deleteTool := mcp.NewTool(
+		"scratchpad_delete",
+		mcp.WithDescription("removes a note from this session's scratchpad, or all of them when no key is given"),
+		mcp.WithString("key", mcp.Description("Name of the note")),
+	)
+	s.AddTool(deleteTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pad, err := states.FromContext(ctx)
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("no session", err), nil
+		}
+		key := request.GetString("key", "")
+		if key == "" {
+			err = pad.Clear(ctx)
+		} else {
+			err = pad.Delete(ctx, key)
+		}
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("delete failed", err), nil
+		}
+		return mcp.NewToolResultText("deleted"), nil
+	})
+}
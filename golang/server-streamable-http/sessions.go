@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/duaraghav8/gomcptest-server/registry"
+	"github.com/duaraghav8/gomcptest-server/store"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	methodResourcesSubscribe   = "resources/subscribe"
+	methodResourcesUnsubscribe = "resources/unsubscribe"
+)
+
+// persistSessions records what each client negotiates in st, and restores it
+// whenever a request arrives for a session, so that a replica which did not
+// handle the initialize request still sees the right log level and
+// capabilities. Only streamable HTTP sessions are kept in st; SSE and stdio
+// sessions live on a single connection and are skipped.
+//
+// A request also refreshes the record's UpdatedAt once it is a quarter of ttl
+// old, so that pruneSessions leaves it alone.
+func persistSessions(st store.SessionStore, hooks *server.Hooks, roots *sessionRoots, ttl time.Duration) {
+	hooks.AddAfterInitialize(func(ctx context.Context, id any, req *mcp.InitializeRequest, res *mcp.InitializeResult) {
+		sess := server.ClientSessionFromContext(ctx)
+		if sess == nil {
+			return
+		}
+		err := st.Update(ctx, sess.SessionID(), func(s *store.Session) error {
+			s.ProtocolVersion = res.ProtocolVersion
+			s.ClientInfo = req.Params.ClientInfo
+			s.ClientCapabilities = req.Params.Capabilities
+			return nil
+		})
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			log.Printf("[store] %s: save initialize: %v", sess.SessionID(), err)
+		}
+	})
+
+	hooks.AddAfterSetLevel(func(ctx context.Context, id any, req *mcp.SetLevelRequest, _ *mcp.EmptyResult) {
+		sess := server.ClientSessionFromContext(ctx)
+		if sess == nil {
+			return
+		}
+		err := st.Update(ctx, sess.SessionID(), func(s *store.Session) error {
+			s.LogLevel = req.Params.Level
+			return nil
+		})
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			log.Printf("[store] %s: save log level: %v", sess.SessionID(), err)
+		}
+	})
+
+	hooks.AddBeforeAny(func(ctx context.Context, id any, method mcp.MCPMethod, message any) {
+		if method == mcp.MethodInitialize {
+			return
+		}
+		sess := server.ClientSessionFromContext(ctx)
+		if sess == nil || sess.SessionID() == "" {
+			return
+		}
+		saved, err := st.Get(ctx, sess.SessionID())
+		if err != nil {
+			return
+		}
+		if time.Since(saved.UpdatedAt) > ttl/4 {
+			err := st.Update(ctx, sess.SessionID(), func(*store.Session) error { return nil })
+			if err != nil && !errors.Is(err, store.ErrNotFound) {
+				log.Printf("[store] %s: touch: %v", sess.SessionID(), err)
+			}
+		}
+		if logging, ok := sess.(server.SessionWithLogging); ok && saved.LogLevel != "" {
+			if logging.GetLogLevel() != saved.LogLevel {
+				logging.SetLogLevel(saved.LogLevel)
+			}
+		}
+		roots.setCapable(sess.SessionID(), saved.ClientCapabilities.Roots != nil)
+	})
+}
+
+// resourceSubscriptions answers resources/subscribe and
+// resources/unsubscribe, which mcp-go does not implement, by recording the
+// subscription in the session store.
+func resourceSubscriptions(st store.SessionStore) rpcMethod {
+	return func(w http.ResponseWriter, r *http.Request, req rpcRequest) {
+		var params struct {
+			URI string `json:"uri"`
+		}
+		if !req.params(w, &params) {
+			return
+		}
+
+		sessionID := r.Header.Get(server.HeaderKeySessionID)
+		if err := store.ValidateID(sessionID); err != nil {
+			http.Error(w, "Invalid session ID", http.StatusBadRequest)
+			return
+		}
+		if params.URI == "" {
+			writeJSON(w, mcp.NewJSONRPCError(req.ID, mcp.INVALID_PARAMS, "missing uri", nil))
+			return
+		}
+
+		err := st.Update(r.Context(), sessionID, func(s *store.Session) error {
+			if req.Method == methodResourcesSubscribe {
+				s.Subscribe(params.URI)
+			} else {
+				s.Unsubscribe(params.URI)
+			}
+			return nil
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Session terminated", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			writeJSON(w, mcp.NewJSONRPCError(req.ID, mcp.INTERNAL_ERROR, err.Error(), nil))
+			return
+		}
+		writeJSON(w, mcp.NewJSONRPCResultResponse(req.ID, mcp.EmptyResult{}))
+	}
+}
+
+// terminateSession ends a session for good: its streams are closed and, for
+// streamable HTTP, its record is deleted from st so the client gets 404 and
+// has to initialize again.
+func terminateSession(ctx context.Context, st store.SessionStore, reg *registry.Registry, sessionID string) error {
+	if err := st.Delete(ctx, sessionID); err != nil {
+		return fmt.Errorf("terminate session: %w", err)
+	}
+	reg.Disconnect(ctx, sessionID)
+	return nil
+}
+
+// defaultRecordTTL is how long a session's record outlives its last request
+// when sessions have no idle TTL. Clients that go away without DELETE never
+// end their session otherwise, and the record would stay for good.
+const defaultRecordTTL = 24 * time.Hour
+
+// recordTTL is how long pruneSessions keeps the record of a session no
+// request has touched.
+func recordTTL(idleTTL time.Duration) time.Duration {
+	if idleTTL > 0 {
+		return idleTTL
+	}
+	return defaultRecordTTL
+}
+
+// pruneSessions deletes the records of sessions that ended without being
+// terminated: those no request has touched for ttl, because their client
+// went away without DELETE, or while another replica served them or this
+// one restarted. It checks once at start and then every ttl/4, until ctx is
+// done.
+func pruneSessions(ctx context.Context, st store.SessionStore, ttl time.Duration) {
+	for {
+		all, err := st.List(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("[store] prune sessions: %v", err)
+		}
+		now := time.Now()
+		for _, s := range all {
+			if now.Sub(s.UpdatedAt) <= ttl {
+				continue
+			}
+			if err := st.Delete(ctx, s.ID); err != nil {
+				log.Printf("[store] prune session %s: %v", s.ID, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(max(ttl/4, time.Second)):
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
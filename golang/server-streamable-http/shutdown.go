@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/duaraghav8/gomcptest-server/registry"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// shutdownNoticeGrace is how long streams are kept open after the shutdown
+// notification is queued, so it reaches clients before the connection drops.
+const shutdownNoticeGrace = 500 * time.Millisecond
+
+// drainGate sits in front of the HTTP transports. It counts in-flight POST
+// requests so shutdown can wait for them, and once draining has started it
+// turns away anything that would open a new session or stream.
+type drainGate struct {
+	next     http.Handler
+	draining atomic.Bool
+	inflight atomic.Int64
+}
+
+func newDrainGate(next http.Handler) *drainGate {
+	return &drainGate{next: next}
+}
+
+func (g *drainGate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if g.draining.Load() {
+		newSession := r.Method == http.MethodPost && registry.RequestSessionID(r) == ""
+		if newSession || r.Method == http.MethodGet {
+			w.Header().Set("Connection", "close")
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	// GET streams live as long as the client stays connected, they are closed
+	// by shutdown rather than waited for.
+	if r.Method == http.MethodPost {
+		g.inflight.Add(1)
+		defer g.inflight.Add(-1)
+	}
+	g.next.ServeHTTP(w, r)
+}
+
+// close turns away new sessions and streams from now on.
+func (g *drainGate) close() {
+	g.draining.Store(true)
+}
+
+// drain waits until no POST request is in flight or ctx expires.
+func (g *drainGate) drain(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		n := g.inflight.Load()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("%d request(s) still in flight: %w", n, ctx.Err())
+		}
+	}
+}
+
+// gracefulShutdown takes the server down in order: refuse new sessions, tell
+// connected clients we are going away, let in-flight calls finish within
+// timeout, close the remaining streams and finally flush metrics and logs.
+// httpServer is nil when only stdio is served; closeStreams also ends the
+// stdio transport.
+func gracefulShutdown(
+	s *server.MCPServer,
+	gate *drainGate,
+	httpServer *http.Server,
+	closeStreams context.CancelFunc,
+	m *metrics,
+	timeout time.Duration,
+) {
+	deadline := time.Now().Add(timeout)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	log.Printf("Shutting down, draining in-flight requests for up to %s...", timeout)
+	// Close the gate before the notice, so no session can start after its
+	// clients were told to go away.
+	gate.close()
+	s.SendNotificationToAllClients("server/shutdown", map[string]any{
+		"reason":   "server is shutting down",
+		"deadline": deadline.Format(time.RFC3339),
+	})
+
+	if err := gate.drain(ctx); err != nil {
+		log.Printf("Drain incomplete: %v", err)
+	}
+
+	// Give the listening streams a moment to deliver the shutdown notice.
+	select {
+	case <-time.After(shutdownNoticeGrace):
+	case <-ctx.Done():
+	}
+
+	// Listening streams never go idle on their own, so end them before
+	// asking the HTTP server to wait for idle connections.
+	closeStreams()
+	if httpServer != nil {
+		closeCtx, closeCancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer closeCancel()
+		if err := httpServer.Shutdown(closeCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("HTTP shutdown error: %v", err)
+			httpServer.Close()
+		}
+	}
+
+	m.flush()
+	log.Println("Server stopped.")
+	os.Stdout.Sync()
+	os.Stderr.Sync()
+}
@@ -0,0 +1,254 @@
+// Package state gives tool handlers a small key-value store scoped to the
+// client session they are serving, so a multi-step workflow such as a cart or
+// a scratchpad can remember what earlier calls did.
+//
+// Values are stored as JSON in the session's record in a store.SessionStore,
+// which makes them as durable as the sessions themselves: in memory by
+// default, on disk and shared between replicas with a FileStore. Sessions the
+// store does not know, those on the SSE and stdio transports, keep their state
+// in process memory until they disconnect.
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/duaraghav8/gomcptest-server/store"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+var (
+	// ErrTooManyKeys is returned by Set when the session already holds
+	// Limits.MaxKeys keys.
+	ErrTooManyKeys = errors.New("session state has too many keys")
+	// ErrTooLarge is returned by Set when the session's state would grow
+	// past Limits.MaxBytes.
+	ErrTooLarge = errors.New("session state is too large")
+)
+
+// Limits bound the state of a single session. Zero values mean no limit.
+type Limits struct {
+	// MaxKeys is the number of live keys a session may hold.
+	MaxKeys int
+	// MaxBytes is the total size of a session's keys and JSON values.
+	MaxBytes int
+	// DefaultTTL applies to values set without a TTL.
+	DefaultTTL time.Duration
+}
+
+// Manager hands out the state of each session.
+type Manager struct {
+	store  store.SessionStore
+	limits Limits
+
+	mu     sync.Mutex
+	memory map[string]map[string]store.StateEntry // sessionID -> state of sessions st does not know
+}
+
+// NewManager returns a Manager that keeps state in st where it can.
+func NewManager(st store.SessionStore, limits Limits) *Manager {
+	return &Manager{
+		store:  st,
+		limits: limits,
+		memory: make(map[string]map[string]store.StateEntry),
+	}
+}
+
+// Register drops the in-memory state of a session when it goes away. State
+// kept in the session store lives as long as the session record.
+func (m *Manager) Register(hooks *server.Hooks) {
+	hooks.AddOnUnregisterSession(func(ctx context.Context, sess server.ClientSession) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.memory, sess.SessionID())
+	})
+}
+
+// FromContext returns the state of the session whose request is being
+// handled in ctx.
+func (m *Manager) FromContext(ctx context.Context) (*Session, error) {
+	sess := server.ClientSessionFromContext(ctx)
+	if sess == nil || sess.SessionID() == "" {
+		return nil, server.ErrNoClientSession
+	}
+	return &Session{m: m, id: sess.SessionID()}, nil
+}
+
+// update applies fn to a copy of the session's live entries and saves the
+// result if fn succeeds. Expired entries are dropped on the way.
+func (m *Manager) update(ctx context.Context, sessionID string, fn func(map[string]store.StateEntry) error) error {
+	apply := func(entries map[string]store.StateEntry) (map[string]store.StateEntry, error) {
+		now := time.Now()
+		live := make(map[string]store.StateEntry, len(entries))
+		for k, e := range entries {
+			if !e.Expired(now) {
+				live[k] = e
+			}
+		}
+		if err := fn(live); err != nil {
+			return nil, err
+		}
+		return live, nil
+	}
+
+	err := m.store.Update(ctx, sessionID, func(s *store.Session) error {
+		updated, err := apply(s.State)
+		if err != nil {
+			return err
+		}
+		s.State = updated
+		return nil
+	})
+	if !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	updated, err := apply(m.memory[sessionID])
+	if err != nil {
+		return err
+	}
+	m.memory[sessionID] = updated
+	return nil
+}
+
+// view calls fn with the session's live entries without saving anything.
+func (m *Manager) view(ctx context.Context, sessionID string, fn func(map[string]store.StateEntry)) error {
+	var entries map[string]store.StateEntry
+	s, err := m.store.Get(ctx, sessionID)
+	switch {
+	case err == nil:
+		entries = s.State
+	case errors.Is(err, store.ErrNotFound):
+		m.mu.Lock()
+		entries = maps.Clone(m.memory[sessionID])
+		m.mu.Unlock()
+	default:
+		return err
+	}
+
+	now := time.Now()
+	maps.DeleteFunc(entries, func(_ string, e store.StateEntry) bool { return e.Expired(now) })
+	fn(entries)
+	return nil
+}
+
+func (m *Manager) checkLimits(entries map[string]store.StateEntry) error {
+	if m.limits.MaxKeys > 0 && len(entries) > m.limits.MaxKeys {
+		return fmt.Errorf("%w: limit is %d", ErrTooManyKeys, m.limits.MaxKeys)
+	}
+	if m.limits.MaxBytes > 0 {
+		size := 0
+		for k, e := range entries {
+			size += len(k) + len(e.Value)
+		}
+		if size > m.limits.MaxBytes {
+			return fmt.Errorf("%w: %d bytes, limit is %d", ErrTooLarge, size, m.limits.MaxBytes)
+		}
+	}
+	return nil
+}
+
+// Session is the key-value state of one session.
+type Session struct {
+	m  *Manager
+	id string
+}
+
+// Get decodes the value stored under key into v. It reports false if the
+// key is not set or has expired.
+func (s *Session) Get(ctx context.Context, key string, v any) (bool, error) {
+	var (
+		raw   json.RawMessage
+		found bool
+	)
+	err := s.m.view(ctx, s.id, func(entries map[string]store.StateEntry) {
+		var e store.StateEntry
+		e, found = entries[key]
+		raw = e.Value
+	})
+	if err != nil || !found {
+		return false, err
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+// Set stores v under key. The value expires after ttl, or after the
+// manager's default TTL when ttl is zero.
+func (s *Session) Set(ctx context.Context, key string, v any, ttl time.Duration) error {
+	entry, err := s.entry(v, ttl)
+	if err != nil {
+		return err
+	}
+	return s.m.update(ctx, s.id, func(entries map[string]store.StateEntry) error {
+		entries[key] = entry
+		return s.m.checkLimits(entries)
+	})
+}
+
+// Update stores under key what fn makes of the current value, in a single
+// update of the session's state, so that concurrent updates of the same key
+// do not lose each other's changes. fn gets the stored JSON, or nil if the
+// key is not set or has expired. The TTL applies as for Set.
+func (s *Session) Update(ctx context.Context, key string, ttl time.Duration, fn func(old json.RawMessage) (any, error)) error {
+	return s.m.update(ctx, s.id, func(entries map[string]store.StateEntry) error {
+		v, err := fn(entries[key].Value)
+		if err != nil {
+			return err
+		}
+		entry, err := s.entry(v, ttl)
+		if err != nil {
+			return err
+		}
+		entries[key] = entry
+		return s.m.checkLimits(entries)
+	})
+}
+
+// entry encodes v with the expiry that ttl, or the default TTL, gives it.
+func (s *Session) entry(v any, ttl time.Duration) (store.StateEntry, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return store.StateEntry{}, err
+	}
+	if ttl == 0 {
+		ttl = s.m.limits.DefaultTTL
+	}
+	entry := store.StateEntry{Value: raw}
+	if ttl > 0 {
+		entry.ExpiresAt = time.Now().Add(ttl)
+	}
+	return entry, nil
+}
+
+// Delete removes key. Removing a missing key is not an error.
+func (s *Session) Delete(ctx context.Context, key string) error {
+	return s.m.update(ctx, s.id, func(entries map[string]store.StateEntry) error {
+		delete(entries, key)
+		return nil
+	})
+}
+
+// Keys returns the live keys in sorted order.
+func (s *Session) Keys(ctx context.Context) ([]string, error) {
+	var keys []string
+	err := s.m.view(ctx, s.id, func(entries map[string]store.StateEntry) {
+		keys = slices.Sorted(maps.Keys(entries))
+	})
+	return keys, err
+}
+
+// Clear removes every key.
+func (s *Session) Clear(ctx context.Context) error {
+	return s.m.update(ctx, s.id, func(entries map[string]store.StateEntry) error {
+		clear(entries)
+		return nil
+	})
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const idPrefix = "mcp-session-"
+
+// IDManager is a server.SessionIdManager backed by a SessionStore. Because
+// validation consults the store rather than process memory, a session
+// survives restarts and is accepted by every replica sharing the store.
+type IDManager struct {
+	store SessionStore
+}
+
+var _ server.SessionIdManager = (*IDManager)(nil)
+
+func NewIDManager(store SessionStore) *IDManager {
+	return &IDManager{store: store}
+}
+
+func (m *IDManager) Generate() string {
+	id := idPrefix + uuid.New().String()
+	if err := m.store.Create(context.Background(), id); err != nil {
+		// The interface has no way to report this; the session will fail
+		// validation on its next request and the client will re-initialize.
+		log.Printf("[store] create session %s: %v", id, err)
+	}
+	return id
+}
+
+// Validate reports unknown sessions as terminated rather than invalid, so the
+// client gets a 404 and starts a new session instead of a bare 400.
+func (m *IDManager) Validate(sessionID string) (isTerminated bool, err error) {
+	if err := ValidateID(sessionID); err != nil {
+		return false, err
+	}
+	_, err = m.store.Get(context.Background(), sessionID)
+	if errors.Is(err, ErrNotFound) {
+		return true, nil
+	}
+	return false, err
+}
+
+// Terminate deletes the session's record. Validate reports the missing
+// record as terminated, so no tombstone needs to be kept.
+func (m *IDManager) Terminate(sessionID string) (isNotAllowed bool, err error) {
+	if err := ValidateID(sessionID); err != nil {
+		return false, err
+	}
+	return false, m.store.Delete(context.Background(), sessionID)
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps sessions in process memory. It is the default when no
+// persistent store is configured, and loses everything on restart.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]Session)}
+}
+
+func (m *MemoryStore) Create(ctx context.Context, id string) error {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[id] = Session{ID: id, CreatedAt: now, UpdatedAt: now}
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sess, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(sess), nil
+}
+
+func (m *MemoryStore) Update(ctx context.Context, id string, fn func(*Session) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sess, ok := m.sessions[id]
+	if !ok {
+		return ErrNotFound
+	}
+	updated := clone(sess)
+	if err := fn(updated); err != nil {
+		return err
+	}
+	updated.UpdatedAt = time.Now()
+	m.sessions[id] = *updated
+	return nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *MemoryStore) List(ctx context.Context) ([]*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]*Session, 0, len(m.sessions))
+	for _, sess := range m.sessions {
+		out = append(out, clone(sess))
+	}
+	return out, nil
+}
+
+// clone copies sess so callers can't modify stored slices and maps.
+func clone(sess Session) *Session {
+	sess.Subscriptions = append([]string(nil), sess.Subscriptions...)
+	sess.State = maps.Clone(sess.State)
+	return &sess
+}
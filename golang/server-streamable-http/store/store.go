@@ -0,0 +1,83 @@
+// Package store persists MCP session state outside the server process, so a
+// session created by one replica of the streamable HTTP server can be served
+// by another one, or by the same one after a restart.
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ErrNotFound is returned when no session with the given ID is stored.
+var ErrNotFound = errors.New("session not found")
+
+// Session is everything a replica needs to pick up a session it did not
+// create.
+type Session struct {
+	ID                 string                 `json:"id"`
+	ProtocolVersion    string                 `json:"protocolVersion,omitempty"`
+	ClientInfo         mcp.Implementation     `json:"clientInfo"`
+	ClientCapabilities mcp.ClientCapabilities `json:"clientCapabilities"`
+	LogLevel           mcp.LoggingLevel       `json:"logLevel,omitempty"`
+	Subscriptions      []string               `json:"subscriptions,omitempty"`
+	State              map[string]StateEntry  `json:"state,omitempty"`
+	CreatedAt          time.Time              `json:"createdAt"`
+	UpdatedAt          time.Time              `json:"updatedAt"`
+}
+
+// StateEntry is one value of a session's key-value state, see package state.
+type StateEntry struct {
+	Value     json.RawMessage `json:"value"`
+	ExpiresAt time.Time       `json:"expiresAt,omitzero"`
+}
+
+// Expired reports whether the entry has a TTL that ran out before now.
+func (e StateEntry) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// Subscribe adds uri to the session's resource subscriptions.
+func (s *Session) Subscribe(uri string) {
+	if !slices.Contains(s.Subscriptions, uri) {
+		s.Subscriptions = append(s.Subscriptions, uri)
+	}
+}
+
+// Unsubscribe removes uri from the session's resource subscriptions.
+func (s *Session) Unsubscribe(uri string) {
+	s.Subscriptions = slices.DeleteFunc(s.Subscriptions, func(u string) bool { return u == uri })
+}
+
+// SessionStore is implemented by session persistence backends.
+type SessionStore interface {
+	// Create stores a new session with the given ID.
+	Create(ctx context.Context, id string) error
+	// Get returns the session with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Session, error)
+	// Update applies fn to the stored session and saves the result. fn is
+	// not called, and ErrNotFound is returned, if the session does not exist.
+	Update(ctx context.Context, id string, fn func(*Session) error) error
+	// Delete removes the session. Deleting an unknown session is not an error.
+	Delete(ctx context.Context, id string) error
+	// List returns every stored session.
+	List(ctx context.Context) ([]*Session, error)
+}
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+// ValidateID rejects session IDs that could not have been generated by
+// IDManager. IDs come straight from request headers, and backends use them as
+// keys and file names.
+func ValidateID(id string) error {
+	if !validID.MatchString(id) {
+		return fmt.Errorf("invalid session id %q", id)
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/duaraghav8/gomcptest-server/pubsub"
+	"github.com/duaraghav8/gomcptest-server/state"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type CustomMCPServer struct {
+	*server.MCPServer
+}
+
+func (s *CustomMCPServer) AddTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	log.Printf("********** Adding tool: %s", tool.Name)
+	s.MCPServer.AddTool(tool, handler)
+}
+
+type Person struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+type InputSchema struct {
+	Sender   Person `json:"sender"`
+	Receiver Person `json:"receiver"`
+}
+
+type OutputSchema struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// registerTools adds every tool, resource and prompt to s. All transports
+// serve the same server, so this runs once at startup.
+func registerTools(s *CustomMCPServer, roots *sessionRoots, states *state.Manager, broker *pubsub.Broker) {
+	echoTool := mcp.NewTool(
+		"echo",
+		mcp.WithDescription("echoes back your message"),
+		mcp.WithString(
+			"message",
+			mcp.Description("Your message"),
+			mcp.Required(),
+		),
+	)
+	s.AddTool(echoTool, handleEchoToolCall)
+
+	audioTool := mcp.NewTool(
+		"return_audio",
+		mcp.WithDescription("returns random audio"),
+	)
+	s.AddTool(audioTool, handleAudioToolCall)
+
+	structuredContentTool := mcp.NewTool(
+		"structured_content",
+		mcp.WithDescription("returns structured content"),
+	)
+	s.AddTool(structuredContentTool, handleStructuredContentCall)
+
+	myResource := mcp.NewResource(
+		"file://sample.txt",
+		"sample_text_file",
+		mcp.WithResourceDescription("Sample text file resource"),
+		mcp.WithMIMEType("text/plain"),
+	)
+	s.AddResource(myResource, resourceHandler)
+
+	withSchemaTool := mcp.NewTool(
+		"tool_with_output_schema",
+		mcp.WithDescription("has schema for output"),
+		mcp.WithInputSchema[InputSchema](),
+		mcp.WithOutputSchema[OutputSchema](),
+	)
+	s.AddTool(withSchemaTool, handleWithSchemaCall)
+
+	egPrompt := mcp.NewPrompt(
+		"echo",
+		mcp.WithArgument(
+			"message",
+			mcp.RequiredArgument(),
+			mcp.ArgumentDescription("Message to echo"),
+		),
+	)
+
+	s.AddPrompt(egPrompt, egPromptHandler)
+
+	registerFileTools(s, roots)
+	registerScratchpadTools(s, states)
+	registerTopicTools(s, broker)
+
+	// Tool: ping — echoes a message
+	pingTool := mcp.NewTool(
+		"ping",
+		mcp.WithDescription("Echo a message back (for connectivity checks)."),
+		mcp.WithString("message",
+			mcp.Description("Any text to echo back."),
+			mcp.Required(),
+		),
+	)
+	s.AddTool(pingTool, handlePingToolCall)
+
+	// Tool: add — sums two numbers and returns a structured result
+	addTool := mcp.NewTool(
+		"add",
+		mcp.WithDescription("Return a+b."),
+		mcp.WithNumber("a", mcp.Description("First addend."), mcp.Required()),
+		mcp.WithNumber("b", mcp.Description("Second addend."), mcp.Required()),
+	)
+	s.AddTool(addTool, handleAddToolCall)
+}
+
+func handleEchoToolCall(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Println(request.GetString("message", ""))
+	log.Println(request)
+	arguments := request.GetArguments()
+	message, ok := arguments["message"].(string)
+	log.Println(message, ok)
+	if !ok {
+		return nil, fmt.Errorf("invalid message argument")
+	}
+
+	embedded := mcp.EmbeddedResource{
+		Type: "resource",
+		Resource: mcp.TextResourceContents{
+			URI:      "resource://embedded-tool",
+			MIMEType: "text/plain",
+			Text:     "This is embedded resource content!",
+		},
+		Meta: nil,
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("Echo: %s", message),
+			},
+			mcp.NewResourceLink(
+				"file:///example/resource.txt",
+				"sample text",
+				"An example text resource",
+				"text/plain",
+			),
+			embedded,
+		},
+	}, nil
+}
+
+func handleAudioToolCall(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	const (
+		sampleRate = 8000
+		duration   = 1 // seconds
+		numSamples = sampleRate * duration
+	)
+
+	// WAV header for 16-bit PCM mono
+	var buf bytes.Buffer
+	// RIFF header
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+numSamples*2))
+	buf.WriteString("WAVE")
+	// fmt chunk
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16)) // Subchunk1Size
+	binary.Write(&buf, binary.LittleEndian, uint16(1))  // AudioFormat PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(1))  // NumChannels
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2)) // ByteRate
+	binary.Write(&buf, binary.LittleEndian, uint16(2))            // BlockAlign
+	binary.Write(&buf, binary.LittleEndian, uint16(16))           // BitsPerSample
+	// data chunk
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(numSamples*2))
+
+	// Write random samples
+	rand.Seed(time.Now().UnixNano())
+	for i := 0; i < numSamples; i++ {
+		sample := int16(rand.Intn(65536) - 32768)
+		binary.Write(&buf, binary.LittleEndian, sample)
+	}
+
+	data := buf.Bytes()
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewAudioContent(encoded, "audio/wav"),
+		},
+	}, nil
+}
+
+func handleStructuredContentCall(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	structuredData := map[string]interface{}{
+		"title":       "Sample Structured Content",
+		"description": "This is an example of structured content returned by a tool.",
+		"items": []map[string]interface{}{
+			{
+				"id":    1,
+				"name":  "Item One",
+				"value": 100,
+			},
+			{
+				"id":    2,
+				"name":  "Item Two",
+				"value": 200,
+			},
+		},
+	}
+
+	return mcp.NewToolResultStructured(map[string]any{"data": structuredData}, "Returned structured data"), nil
+}
+
+func resourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      "file://sample.txt",
+			MIMEType: "text/plain",
+			Text:     "Hello, this is a sample text file content.",
+		},
+	}, nil
+}
+
+func handleWithSchemaCall(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	output := OutputSchema{
+		Title:       "Sample Title",
+		Description: "This is a sample description.",
+	}
+	return mcp.NewToolResultStructured(output, "Data returned in structured format"), nil
+}
+
+func egPromptHandler(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	message, ok := request.Params.Arguments["message"]
+	if !ok {
+		message = "No message provided"
+	}
+
+	messages := []mcp.PromptMessage{
+		{
+			Role:    mcp.RoleAssistant,
+			Content: mcp.NewTextContent("Your message: " + message),
+		},
+	}
+	return &mcp.GetPromptResult{
+		Result:      mcp.Result{},
+		Description: "Yeh le tera prompt result",
+		Messages:    messages,
+	}, nil
+}
+
+func handlePingToolCall(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	logger := loggerFromContext(ctx, "ping")
+	msg := req.GetString("message", "")
+	if msg == "" {
+		logger.Warningf("called without a message")
+		return mcp.NewToolResultError("missing required arg: message"), nil
+	}
+	logger.Debugf("echoing %d bytes", len(msg))
+	return mcp.NewToolResultText("pong: " + msg), nil
+}
+
+func handleAddToolCall(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	a := req.GetFloat("a", 0)
+	b := req.GetFloat("b", 0)
+	loggerFromContext(ctx, "add").Infof("%g + %g = %g", a, b, a+b)
+	return mcp.NewToolResultStructured(map[string]any{
+		"sum": a + b,
+	}, "sum computed"), nil
+}
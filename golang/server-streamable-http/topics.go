@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/duaraghav8/gomcptest-server/pubsub"
+	"github.com/duaraghav8/gomcptest-server/registry"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	methodTopicsSubscribe   = "topics/subscribe"
+	methodTopicsUnsubscribe = "topics/unsubscribe"
+)
+
+// registerTopicTools adds tools to subscribe the caller to topics. They work
+// on every transport; streamable HTTP clients can also send the
+// topics/subscribe and topics/unsubscribe requests answered by topicRequests.
+func registerTopicTools(s *CustomMCPServer, broker *pubsub.Broker) {
+	subscribeTool := mcp.NewTool(
+		"topic_subscribe",
+		mcp.WithDescription("subscribes this session to a topic, messages published to it arrive as "+pubsub.MethodTopicMessage+" notifications"),
+		mcp.WithString("topic", mcp.Description("Name of the topic"), mcp.Required()),
+	)
+	s.AddTool(subscribeTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		sess := server.ClientSessionFromContext(ctx)
+		if sess == nil {
+			return mcp.NewToolResultErrorFromErr("no session", server.ErrNoClientSession), nil
+		}
+		topic := request.GetString("topic", "")
+		if err := broker.Subscribe(sess.SessionID(), topic); err != nil {
+			return mcp.NewToolResultErrorFromErr("subscribe failed", err), nil
+		}
+		loggerFromContext(ctx, "pubsub").Debugf("subscribed to %s", topic)
+		return mcp.NewToolResultText("subscribed to " + topic), nil
+	})
+
+	unsubscribeTool := mcp.NewTool(
+		"topic_unsubscribe",
+		mcp.WithDescription("unsubscribes this session from a topic"),
+		mcp.WithString("topic", mcp.Description("Name of the topic"), mcp.Required()),
+	)
+	s.AddTool(unsubscribeTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		sess := server.ClientSessionFromContext(ctx)
+		if sess == nil {
+			return mcp.NewToolResultErrorFromErr("no session", server.ErrNoClientSession), nil
+		}
+		topic := request.GetString("topic", "")
+		broker.Unsubscribe(sess.SessionID(), topic)
+		return mcp.NewToolResultText("unsubscribed from " + topic), nil
+	})
+
+	listTool := mcp.NewTool(
+		"topic_list",
+		mcp.WithDescription("lists the topics this session is subscribed to"),
+	)
+	s.AddTool(listTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		sess := server.ClientSessionFromContext(ctx)
+		if sess == nil {
+			return mcp.NewToolResultErrorFromErr("no session", server.ErrNoClientSession), nil
+		}
+		return mcp.NewToolResultText(strings.Join(broker.Topics(sess.SessionID()), "\n")), nil
+	})
+}
+
+// topicRequests answers the topics/subscribe and topics/unsubscribe
+// requests, which mcp-go has no way to register, on the streamable HTTP
+// endpoint.
+func topicRequests(broker *pubsub.Broker, sessions *registry.Registry) rpcMethod {
+	return func(w http.ResponseWriter, r *http.Request, req rpcRequest) {
+		var params struct {
+			Topic string `json:"topic"`
+		}
+		if !req.params(w, &params) {
+			return
+		}
+
+		sessionID := r.Header.Get(server.HeaderKeySessionID)
+		if _, ok := sessions.Get(sessionID); !ok {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		if req.Method == methodTopicsUnsubscribe {
+			broker.Unsubscribe(sessionID, params.Topic)
+		} else if err := broker.Subscribe(sessionID, params.Topic); err != nil {
+			writeJSON(w, mcp.NewJSONRPCError(req.ID, mcp.INVALID_PARAMS, err.Error(), nil))
+			return
+		}
+		writeJSON(w, mcp.NewJSONRPCResultResponse(req.ID, mcp.EmptyResult{}))
+	}
+}
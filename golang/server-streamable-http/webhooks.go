@@ -0,0 +1,309 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxWebhookBody bounds the size of an inbound webhook.
+const maxWebhookBody = 1 << 20
+
+// webhookTolerance is how far a webhook's timestamp may be from the server's
+// clock. Signatures are remembered meanwhile, so each request is accepted
+// once.
+const webhookTolerance = 5 * time.Minute
+
+// webhooksFile is the format of the file named by -webhooks-file:
+//
+//	{"webhooks": [
+//	  {"name": "ci", "secrets_env": "CI_WEBHOOK_SECRET",
+//	   "signature_header": "X-CI-Signature", "timestamp_header": "X-CI-Timestamp",
+//	   "method": "notifications/ci"},
+//	  {"name": "tickets", "secrets": ["s3cret"], "topic": "tickets"},
+//	  {"name": "docs", "secrets": ["s3cret"], "algorithm": "sha1",
+//	   "resource": "docs://{repository.name}/readme"}
+//	]}
+//
+// Each webhook is served at /hooks/<name> and maps to exactly one of a
+// notification method, whose params are the JSON payload, a topic the
+// payload is published to, or a resource URI whose subscribers are told it
+// was updated. {field.path} in the URI is filled in from the payload.
+//
+// The sender signs the timestamp header, in Unix seconds, and the body,
+// joined by a dot, and the server accepts each signed request once and only
+// within webhookTolerance of its timestamp, so a captured request cannot be
+// replayed.
+type webhooksFile struct {
+	Webhooks []webhookConfig `json:"webhooks"`
+}
+
+type webhookConfig struct {
+	Name string `json:"name"`
+	// Secrets verify the signature; any of them may have signed it, so a
+	// secret can be rotated without downtime.
+	Secrets []string `json:"secrets"`
+	// SecretsEnv names an environment variable holding comma-separated
+	// secrets, to keep them out of the file.
+	SecretsEnv      string `json:"secrets_env"`
+	SignatureHeader string `json:"signature_header"`
+	TimestampHeader string `json:"timestamp_header"`
+	Algorithm       string `json:"algorithm"`
+	Method          string `json:"method"`
+	Topic           string `json:"topic"`
+	Resource        string `json:"resource"`
+}
+
+var hashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+var validHookName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// webhooks verifies inbound webhooks and turns them into MCP notifications.
+type webhooks struct {
+	actions *actionRunner
+	hooks   map[string]*webhookConfig
+
+	mu   sync.Mutex
+	seen map[string]time.Time // webhook name and hex HMAC -> when first seen
+}
+
+// loadWebhooks reads the webhooks file at path.
+func loadWebhooks(path string, actions *actionRunner) (*webhooks, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var f webhooksFile
+	dec := json.NewDecoder(file)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	wh := &webhooks{
+		actions: actions,
+		hooks:   make(map[string]*webhookConfig),
+		seen:    make(map[string]time.Time),
+	}
+	var errs []error
+	for i := range f.Webhooks {
+		hc := &f.Webhooks[i]
+		if err := hc.prepare(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: webhook %d (%s): %w", path, i+1, hc.Name, err))
+			continue
+		}
+		if _, ok := wh.hooks[hc.Name]; ok {
+			errs = append(errs, fmt.Errorf("%s: webhook %q defined twice", path, hc.Name))
+			continue
+		}
+		wh.hooks[hc.Name] = hc
+	}
+	return wh, errors.Join(errs...)
+}
+
+// prepare validates the config and fills in defaults.
+func (hc *webhookConfig) prepare() error {
+	if !validHookName.MatchString(hc.Name) {
+		return errors.New("name must be letters, digits, '.', '_' or '-'")
+	}
+	if hc.SecretsEnv != "" {
+		hc.Secrets = append(hc.Secrets, splitList(os.Getenv(hc.SecretsEnv))...)
+	}
+	if len(hc.Secrets) == 0 {
+		return errors.New("needs at least one secret")
+	}
+	if slices.Contains(hc.Secrets, "") {
+		return errors.New("secrets must not be empty")
+	}
+	if hc.SignatureHeader == "" {
+		hc.SignatureHeader = "X-Signature-256"
+	}
+	if hc.TimestampHeader == "" {
+		hc.TimestampHeader = "X-Signature-Timestamp"
+	}
+	if hc.Algorithm == "" {
+		hc.Algorithm = "sha256"
+	}
+	if _, ok := hashes[hc.Algorithm]; !ok {
+		return fmt.Errorf("unknown algorithm %q, use sha1, sha256 or sha512", hc.Algorithm)
+	}
+	targets := 0
+	for _, t := range []string{hc.Method, hc.Topic, hc.Resource} {
+		if t != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return errors.New("needs exactly one of method, topic or resource")
+	}
+	return nil
+}
+
+// verify checks the request's signature against every secret, and that its
+// timestamp is within webhookTolerance of now. The signature header holds the
+// hex HMAC of "<timestamp>.<body>", optionally prefixed with "<algorithm>=".
+// It returns the decoded HMAC of a valid request.
+func (hc *webhookConfig) verify(r *http.Request, body []byte, now time.Time) ([]byte, bool) {
+	ts := r.Header.Get(hc.TimestampHeader)
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > webhookTolerance || d < -webhookTolerance {
+		return nil, false
+	}
+	sig := strings.TrimPrefix(r.Header.Get(hc.SignatureHeader), hc.Algorithm+"=")
+	got, err := hex.DecodeString(sig)
+	if err != nil || len(got) == 0 {
+		return nil, false
+	}
+	for _, secret := range hc.Secrets {
+		mac := hmac.New(hashes[hc.Algorithm], []byte(secret))
+		mac.Write([]byte(ts + "."))
+		mac.Write(body)
+		if hmac.Equal(got, mac.Sum(nil)) {
+			return got, true
+		}
+	}
+	return nil, false
+}
+
+// firstSeen records the HMAC of a verified request and reports whether it
+// is new. It is keyed on the decoded HMAC rather than the header, which may
+// spell the same one with or without the algorithm prefix. An HMAC is
+// forgotten after twice webhookTolerance, the longest a timestamp stays
+// acceptable, as verify turns its request away by then.
+func (wh *webhooks) firstSeen(hc *webhookConfig, mac []byte, now time.Time) bool {
+	key := hc.Name + " " + hex.EncodeToString(mac)
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+	for k, at := range wh.seen {
+		if now.Sub(at) > 2*webhookTolerance {
+			delete(wh.seen, k)
+		}
+	}
+	if _, ok := wh.seen[key]; ok {
+		return false
+	}
+	wh.seen[key] = now
+	return true
+}
+
+// routes mounts the webhooks on mux under prefix.
+func (wh *webhooks) routes(mux *http.ServeMux, prefix string) {
+	mux.HandleFunc("POST "+prefix+"/{name}", wh.handle)
+}
+
+func (wh *webhooks) handle(w http.ResponseWriter, r *http.Request) {
+	hc, ok := wh.hooks[r.PathValue("name")]
+	if !ok {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "read request body", http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	mac, ok := hc.verify(r, body, now)
+	if !ok {
+		log.Printf("[webhooks] %s: bad signature or timestamp from %s", hc.Name, r.RemoteAddr)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	if !wh.firstSeen(hc, mac, now) {
+		log.Printf("[webhooks] %s: replayed request from %s", hc.Name, r.RemoteAddr)
+		http.Error(w, "request already received", http.StatusConflict)
+		return
+	}
+	var payload any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, fmt.Sprintf("invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var result string
+	switch {
+	case hc.Method != "":
+		params, ok := payload.(map[string]any)
+		if !ok {
+			params = map[string]any{"data": payload}
+		}
+		result = wh.actions.notify(&notifyAction{Method: hc.Method, Params: params})
+	case hc.Topic != "":
+		n := wh.actions.broker.Publish(hc.Topic, payload)
+		result = fmt.Sprintf("published to %s, %d subscriber(s)", hc.Topic, n)
+	default:
+		uri, err := expandTemplate(hc.Resource, payload)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		n, err := wh.actions.resourceUpdated(r.Context(), uri)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = fmt.Sprintf("%s updated, %d subscriber(s) notified", uri, n)
+	}
+	log.Printf("[webhooks] %s: %s", hc.Name, result)
+	writeJSON(w, map[string]string{"result": result})
+}
+
+var templateField = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// expandTemplate replaces every {a.b.c} in tmpl with that field of payload.
+func expandTemplate(tmpl string, payload any) (string, error) {
+	var missing []string
+	out := templateField.ReplaceAllStringFunc(tmpl, func(m string) string {
+		path := m[1 : len(m)-1]
+		v := payload
+		for _, key := range strings.Split(path, ".") {
+			obj, ok := v.(map[string]any)
+			if !ok {
+				v = nil
+				break
+			}
+			v = obj[key]
+		}
+		switch v := v.(type) {
+		case string:
+			return v
+		case float64, bool:
+			return fmt.Sprint(v)
+		}
+		missing = append(missing, path)
+		return m
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("payload has no value for %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/duaraghav8/gomcptest/reconnect"
+	"github.com/duaraghav8/mcp-sse-example/topics"
+	mcpc "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// connection is a client that keeps its session across lost connections. The
+// reconnect transport dials again, replays the initialize handshake and
+// subscribes to the same resources; the log level and topic subscription,
+// which it does not know about, are set up again here.
+type connection struct {
+	url   string
+	init  mcp.InitializeRequest
+	level mcp.LoggingLevel
+	topic string
+
+	cli  *mcpc.Client
+	info *mcp.InitializeResult
+
+	mu  sync.Mutex // guards sub, which a reconnect may need before dial returns
+	sub *topics.Subscription[map[string]any]
+}
+
+// dial connects, initializes and subscribes. ctx bounds the whole
+// connection, not only the handshake. The server is pinged every interval so
+// that a lost connection is noticed while the client only waits for
+// notifications.
+func (c *connection) dial(ctx context.Context, interval time.Duration) error {
+	trans := reconnect.New(c.dialSSE, reconnect.Options{
+		PingInterval:  interval,
+		OnStateChange: c.stateChanged,
+	})
+	c.cli = mcpc.NewClient(trans)
+	c.cli.OnNotification(printNotification)
+
+	if err := c.cli.Start(ctx); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	var err error
+	c.info, err = c.cli.Initialize(ctx, c.init)
+	if err != nil {
+		c.cli.Close()
+		return fmt.Errorf("initialize: %w", err)
+	}
+	c.setLevel(ctx)
+
+	if c.topic != "" {
+		sub, err := topics.Subscribe[map[string]any](ctx, c.cli, c.topic, 16)
+		if err != nil {
+			c.cli.Close()
+			return fmt.Errorf("subscribe: %w", err)
+		}
+		c.mu.Lock()
+		c.sub = sub
+		c.mu.Unlock()
+		// The subscription outlives reconnects, so one goroutine prints
+		// it for as long as the client runs.
+		go func() {
+			for msg := range sub.Messages() {
+				if msg.Dropped > 0 {
+					log.Printf("[%s] %d message(s) dropped by the server", msg.Topic, msg.Dropped)
+				}
+				fmt.Printf("[%s #%d] %v\n", msg.Topic, msg.Seq, msg.Data)
+			}
+		}()
+	}
+	return nil
+}
+
+// dialSSE opens and starts one SSE connection.
+func (c *connection) dialSSE(ctx context.Context) (transport.Interface, error) {
+	sse, err := transport.NewSSE(c.url)
+	if err != nil {
+		return nil, fmt.Errorf("new SSE transport: %w", err)
+	}
+	if err := sse.Start(ctx); err != nil {
+		return nil, err
+	}
+	return sse, nil
+}
+
+// setLevel asks the server to forward its log messages at or above our
+// level.
+func (c *connection) setLevel(ctx context.Context) {
+	if c.info.Capabilities.Logging == nil {
+		return
+	}
+	levelReq := mcp.SetLevelRequest{}
+	levelReq.Params.Level = c.level
+	if err := c.cli.SetLevel(ctx, levelReq); err != nil {
+		log.Printf("set log level: %v", err)
+	}
+}
+
+// stateChanged reports connection losses and recoveries, and sets a new
+// session up like the first one.
+func (c *connection) stateChanged(state reconnect.State, err error) {
+	switch state {
+	case reconnect.Reconnecting:
+		log.Printf("connection lost (%v), reconnecting", err)
+	case reconnect.Disconnected:
+		log.Printf("could not reconnect: %v", err)
+	case reconnect.Connected:
+		if err != nil {
+			log.Printf("reconnected, but: %v", err)
+		} else {
+			log.Printf("reconnected")
+		}
+		// Not on the transport's reconnecting goroutine, which reports
+		// this and should not wait on requests.
+		go c.restore()
+	}
+}
+
+// restore sets the log level and subscribes to the topic again on a new
+// session.
+func (c *connection) restore() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	c.setLevel(ctx)
+	c.mu.Lock()
+	sub := c.sub
+	c.mu.Unlock()
+	if sub != nil {
+		if err := sub.Resubscribe(ctx); err != nil {
+			log.Printf("resubscribe: %v", err)
+		}
+	}
+}
+
+// close unsubscribes and disconnects, as far as the connection still
+// allows.
+func (c *connection) close() {
+	c.mu.Lock()
+	sub := c.sub
+	c.mu.Unlock()
+	if sub != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		sub.Close(ctx)
+		cancel()
+	}
+	if err := c.cli.Close(); err != nil {
+		log.Printf("close error: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/duaraghav8/gomcptest/render"
+	"github.com/duaraghav8/mcp-config"
+	"github.com/duaraghav8/mcp-sse-example/topics"
+	mcpc "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func main() {
+	opts := config.Client{URL: "http://localhost:9000/sse"}
+	logLevel := flag.String("log-level", string(mcp.LoggingLevelInfo), "minimum level of server log messages to receive")
+	topic := flag.String("topic", "", "topic to subscribe to and print messages from")
+	pingInterval := flag.Duration("ping-interval", 10*time.Second, "how often to ping the server to notice a lost connection")
+	if err := config.Register(flag.CommandLine, &opts).Parse(os.Args[1:]); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := opts.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// ProtocolVersion string is date-based per spec. 2024-11-05 is widely used/accepted.
+	conn := &connection{
+		url: opts.URL,
+		init: mcp.InitializeRequest{
+			Params: mcp.InitializeParams{
+				ProtocolVersion: "2024-11-05",
+				Capabilities:    mcp.ClientCapabilities{}, // fill if you advertise extras
+				ClientInfo:      mcp.Implementation{Name: "mcp-go-sse-client", Version: "0.1.0"},
+			},
+		},
+		level: mcp.LoggingLevel(*logLevel),
+		topic: *topic,
+	}
+
+	// 1) Connect (SSE GET + POST wiring), initialize and subscribe
+	if err := conn.dial(ctx, *pingInterval); err != nil {
+		log.Fatal(err)
+	}
+	defer conn.close()
+	initRes := conn.info
+	fmt.Printf("Server: %s %s (protocol %s)\n",
+		initRes.ServerInfo.Name, initRes.ServerInfo.Version, initRes.ProtocolVersion)
+	if conn.topic != "" {
+		fmt.Printf("Subscribed to %s\n", conn.topic)
+	}
+
+	// 2) List tools
+	toolsRes, err := conn.cli.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		log.Fatalf("list tools: %v", err)
+	}
+	fmt.Println("Tools:")
+	for _, t := range toolsRes.Tools {
+		fmt.Printf("  - %s: %s\n", t.Name, t.Description)
+	}
+
+	// 3) Optionally call a tool if the server exposes one named "ping" or similar
+	//    Change "ping" and arguments to something your server actually implements.
+	tryCall(ctx, conn.cli, "ping", map[string]any{"message": "hello from mcp-go SSE client"})
+
+	// 4) Keep printing notifications until interrupted, reconnecting if the
+	//    server goes away.
+	<-ctx.Done()
+}
+
+// printNotification handles async notifications (server->client) over SSE.
+func printNotification(n mcp.JSONRPCNotification) {
+	switch n.Method {
+	case "notifications/message":
+		printLogMessage(n)
+		return
+	case topics.MethodTopicMessage:
+		// Printed by the subscription.
+		return
+	}
+	log.Printf("notification received: method=%s data=%v", n.Method, n.Params)
+}
+
+// printLogMessage renders a notifications/message sent by the server.
+func printLogMessage(n mcp.JSONRPCNotification) {
+	raw, err := json.Marshal(n.Params)
+	if err != nil {
+		log.Printf("bad log message: %v", err)
+		return
+	}
+	var params mcp.LoggingMessageNotificationParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		log.Printf("bad log message: %v", err)
+		return
+	}
+
+	logger := params.Logger
+	if logger == "" {
+		logger = "server"
+	}
+	data, ok := params.Data.(string)
+	if !ok {
+		b, _ := json.Marshal(params.Data)
+		data = string(b)
+	}
+	log.Printf("[%-9s] %s: %s", params.Level, logger, data)
+}
+
+func tryCall(ctx context.Context, cli *mcpc.Client, tool string, args map[string]any) {
+	res, err := cli.CallTool(ctx, mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name:      tool,
+			Arguments: args,
+		},
+	})
+	if err != nil {
+		log.Printf("call tool %q error: %v", tool, err)
+		return
+	}
+	fmt.Printf("Tool %q result:\n", tool)
+	render.New(os.Stdout, render.Options{}).ToolResult(ctx, res)
+}
@@ -0,0 +1,168 @@
+// Package topics subscribes an MCP client to topics on a server that
+// publishes them as notifications/topic messages, and decodes what arrives
+// into a Go type.
+//
+//	sub, err := topics.Subscribe[BuildEvent](ctx, cli, "ci", 16)
+//	...
+//	for msg := range sub.Messages() {
+//		fmt.Println(msg.Seq, msg.Data.Status)
+//	}
+package topics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	mcpc "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// MethodTopicMessage is the notification method the server publishes with.
+const MethodTopicMessage = "notifications/topic"
+
+// Message is one message published to a topic, with its data decoded as T.
+type Message[T any] struct {
+	Topic string
+	// Seq counts the messages published to Topic, so a gap means messages
+	// were missed.
+	Seq uint64
+	// Dropped is how many messages the server dropped for this session since
+	// it sent the previous one, on any topic.
+	Dropped int
+	Data    T
+}
+
+// wireMessage is the params of a notifications/topic notification.
+type wireMessage struct {
+	Topic   string          `json:"topic"`
+	Seq     uint64          `json:"seq"`
+	Dropped int             `json:"dropped"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Subscription delivers the messages of one topic until it is closed.
+type Subscription[T any] struct {
+	cli   *mcpc.Client
+	topic string
+
+	mu     sync.Mutex
+	ch     chan Message[T]
+	closed bool
+}
+
+// Subscribe asks the server to send messages published to topic and returns
+// a subscription that decodes them as T. Up to buffer messages are held for
+// the caller; when Messages is not drained fast enough newer ones are
+// dropped and logged.
+func Subscribe[T any](ctx context.Context, cli *mcpc.Client, topic string, buffer int) (*Subscription[T], error) {
+	s := &Subscription[T]{cli: cli, topic: topic, ch: make(chan Message[T], buffer)}
+	// The client has no way to remove a handler, so a closed subscription's
+	// handler stays registered and ignores everything.
+	cli.OnNotification(s.handle)
+	if err := s.call(ctx, "topic_subscribe"); err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Topic returns the topic subscribed to.
+func (s *Subscription[T]) Topic() string {
+	return s.topic
+}
+
+// Messages returns the channel messages arrive on. It is closed by Close.
+func (s *Subscription[T]) Messages() <-chan Message[T] {
+	return s.ch
+}
+
+// Close unsubscribes from the topic and closes the Messages channel.
+func (s *Subscription[T]) Close(ctx context.Context) error {
+	if !s.close() {
+		return nil
+	}
+	return s.call(ctx, "topic_unsubscribe")
+}
+
+// Resubscribe asks the server again to send messages published to the topic,
+// as a new session after a reconnect starts with no subscriptions. They keep
+// arriving on the same Messages channel.
+func (s *Subscription[T]) Resubscribe(ctx context.Context) error {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return nil
+	}
+	return s.call(ctx, "topic_subscribe")
+}
+
+func (s *Subscription[T]) close() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.closed = true
+	close(s.ch)
+	return true
+}
+
+func (s *Subscription[T]) handle(n mcp.JSONRPCNotification) {
+	if n.Method != MethodTopicMessage {
+		return
+	}
+	raw, err := json.Marshal(n.Params)
+	if err != nil {
+		return
+	}
+	var wire wireMessage
+	if err := json.Unmarshal(raw, &wire); err != nil || wire.Topic != s.topic {
+		return
+	}
+	msg := Message[T]{Topic: wire.Topic, Seq: wire.Seq, Dropped: wire.Dropped}
+	if err := json.Unmarshal(wire.Data, &msg.Data); err != nil {
+		log.Printf("[topics] %s#%d: decode data: %v", s.topic, wire.Seq, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- msg:
+	default:
+		log.Printf("[topics] %s#%d: dropped, subscriber is not keeping up", s.topic, wire.Seq)
+	}
+}
+
+// call invokes one of the server's topic tools for s.topic.
+func (s *Subscription[T]) call(ctx context.Context, tool string) error {
+	res, err := s.cli.CallTool(ctx, mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name:      tool,
+			Arguments: map[string]any{"topic": s.topic},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", tool, s.topic, err)
+	}
+	if res.IsError {
+		return fmt.Errorf("%s %s: %s", tool, s.topic, resultText(res))
+	}
+	return nil
+}
+
+func resultText(res *mcp.CallToolResult) string {
+	for _, c := range res.Content {
+		if tc, ok := c.(mcp.TextContent); ok {
+			return tc.Text
+		}
+	}
+	return "tool failed"
+}